@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orders/internal/config"
+	"orders/internal/database"
+	"orders/kafka/messaging"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+)
+
+var errUsage = errors.New("usage: main migrate up|down [steps]|status | main dlq replay [limit]")
+
+// runCommand выполняет служебную подкоманду вместо запуска сервиса
+func runCommand(logger *logrus.Logger, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(logger, args[1:])
+	case "dlq":
+		return runDLQ(logger, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q: %w", args[0], errUsage)
+	}
+}
+
+func runMigrate(logger *logrus.Logger, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	postgresCfg, err := config.LoadPostgresConfig(logger)
+	if err != nil {
+		return fmt.Errorf("load postgres config: %w", err)
+	}
+	pool, dbHandler, err := setupDatabase(postgresCfg, logger)
+	if err != nil {
+		return fmt.Errorf("setup database: %w", err)
+	}
+	defer func() {
+		if err := dbHandler.Close(context.Background()); err != nil {
+			logger.Errorf("migrate: failed to close database: %v", err)
+		}
+	}()
+
+	migrator, err := database.NewMigrator(pool, logger)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		logger.Infof("migrate: %d migrations applied", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q: %w", args[1], errUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		logger.Infof("migrate: %d migrations reverted", reverted)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", "-"
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q: %w", args[0], errUsage)
+	}
+	return nil
+}
+
+func runDLQ(logger *logrus.Logger, args []string) error {
+	if len(args) == 0 || args[0] != "replay" {
+		return errUsage
+	}
+	limit := 0
+	if len(args) > 1 {
+		var err error
+		limit, err = strconv.Atoi(args[1])
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid limit %q: %w", args[1], errUsage)
+		}
+	}
+
+	kafkaCfg, err := config.LoadKafkaConfig(logger)
+	if err != nil {
+		return fmt.Errorf("load kafka config: %w", err)
+	}
+	brokers := []string{kafkaCfg.KafkaURL}
+
+	producer := messaging.NewKafkaProducer(brokers)
+	defer func() {
+		if err := producer.Close(context.Background()); err != nil {
+			logger.Errorf("dlq: failed to close producer: %v", err)
+		}
+	}()
+
+	// При чтении нескольких топиков сообщение возвращается в топик, из которого попало в DLQ
+	target := kafkaCfg.Topic
+	if len(kafkaCfg.Topics) > 1 {
+		target = ""
+	}
+	replayer := messaging.NewDLQReplayer(brokers, kafkaCfg.DLQTopic, kafkaCfg.DLQReplayGroup, target, producer, logger)
+	defer func() {
+		if err := replayer.Close(); err != nil {
+			logger.Errorf("dlq: failed to close replayer: %v", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	replayed, err := replayer.Replay(ctx, limit)
+	if target == "" {
+		target = "original topics"
+	}
+	logger.Infof("dlq: %d messages replayed from %s to %s", replayed, kafkaCfg.DLQTopic, target)
+	return err
+}
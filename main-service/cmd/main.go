@@ -3,16 +3,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"orders/internal/config"
 	"orders/internal/database"
+	"orders/internal/grpcapi"
+	"orders/internal/health"
+	"orders/internal/outbox"
 	"orders/internal/subs"
 	"orders/kafka/messaging"
 	"orders/pkg/closer"
 	utilsCfg "orders/pkg/config"
+	"orders/pkg/models"
 	"orders/router"
+	"os"
+	"time"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,32 +29,59 @@ type Application struct {
 	subsService *subs.Service
 	subsHandler *subs.Handler
 	server      *router.Server
+	grpcServer  *grpcapi.Server
 	consumer    messaging.Consumer
-	PostgresCfg *config.PostgresConfig
-	kafkaCfg    *config.KafkaConfig
+	// retryConsumers читают retry-топики; порядок совпадает с kafkaCfg.RetryTiers
+	retryConsumers []messaging.Consumer
+	relay          *outbox.Relay
+	PostgresCfg    *config.PostgresConfig
+	kafkaCfg       *config.KafkaConfig
+	cacheCfg       *config.CacheConfig
 }
 
 func main() {
 	logger := setupLogger()
+
+	if len(os.Args) > 1 {
+		if err := runCommand(logger, os.Args[1:]); err != nil {
+			logger.Fatalf("main: %v", err)
+		}
+		return
+	}
+
 	manager := closer.NewManager(logger)
 
 	app, err := setupApplication(logger, manager)
 	if err != nil {
 		logger.Fatalf("main: Error with Setup Application")
 	}
-	// Cache
-	if err := app.subsService.WarmUpCache(context.Background()); err != nil {
-		logger.Warnf("main: [CACHE]: Failed to warm up: %v", err)
-	}
-	logger.Infof("main: [CACHE]: Warm up")
-
-	// Server
+	// Server: до окончания прогрева кэша /readyz отвечает warming
 	logger.Infof("main: [HTTP SERVER]: Run")
 	go app.server.Run()
+	logger.Infof("main: [GRPC SERVER]: Run")
+	go app.grpcServer.Run()
+	logger.Infof("main: [OUTBOX RELAY]: Run")
+	go app.relay.Run(context.Background())
+	go app.subsService.RunWriteBehind(context.Background())
+
+	go func() {
+		// Cache
+		logger.Infof("main: [CACHE]: Warm up")
+		if err := app.subsService.WarmUpCache(context.Background(), subs.WarmUpOptions{
+			Limit:    app.cacheCfg.WarmUpLimit,
+			Window:   app.cacheCfg.WarmUpWindow,
+			PageSize: app.cacheCfg.WarmUpPageSize,
+		}); err != nil {
+			logger.Warnf("main: [CACHE]: Failed to warm up: %v", err)
+		}
 
-	// Kafka
-	logger.Infof("main: [KAFKA CONSUMER]: Run")
-	go app.consumer.Run(context.Background())
+		// Kafka
+		for _, retryConsumer := range app.retryConsumers {
+			go retryConsumer.Run(context.Background())
+		}
+		logger.Infof("main: [KAFKA CONSUMER]: Run")
+		app.consumer.Run(context.Background())
+	}()
 
 	manager.WaitForSignal()
 	logger.Info("[GLOBAL]: Service stopped..")
@@ -59,43 +94,211 @@ func setupApplication(logger *logrus.Logger, manager *closer.Manager) (*Applicat
 		return nil, fmt.Errorf("load postgres config: %w", err)
 	}
 
-	URL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", postgresCfg.User, postgresCfg.Password, postgresCfg.Host, postgresCfg.Port, postgresCfg.Name)
-	logger.Infof("main.setupApplication: [POSTGRES] Config was Load: %+v\n URL: %s", postgresCfg, URL)
-	conn, dbHandler, err := setupDatabase(URL, logger)
+	logger.Infof("main.setupApplication: [POSTGRES] Config was Load: %+v\n URL: %s", postgresCfg, postgresCfg.URL())
+	pool, dbHandler, err := setupDatabase(postgresCfg, logger)
 	if err != nil {
 		return nil, fmt.Errorf("setup database: %w", err)
 	}
 	manager.Add(dbHandler)
 
-	cache := subs.NewInMemoryCache(logger)
-	subsRepo := subs.NewRepository(conn, logger)
-	subsService := subs.NewService(subsRepo, logger, cache)
-	subsHandler := subs.NewHandler(subsService, logger)
+	if postgresCfg.AutoMigrate {
+		if err := dbHandler.Migrate(context.Background()); err != nil {
+			return nil, fmt.Errorf("migrate database: %w", err)
+		}
+	}
+
+	duplicatePolicy, err := subs.ParseDuplicatePolicy(postgresCfg.DuplicatePolicy)
+	if err != nil {
+		return nil, fmt.Errorf("parse duplicate policy: %w", err)
+	}
+
+	cacheCfg, err := config.LoadCacheConfig(logger)
+	if err != nil {
+		return nil, fmt.Errorf("load cache config: %w", err)
+	}
+	cachePolicy, err := subs.ParseCachePolicy(cacheCfg.Policy)
+	if err != nil {
+		return nil, fmt.Errorf("parse cache policy: %w", err)
+	}
+	cache, err := setupCache(cacheCfg, logger, manager)
+	if err != nil {
+		return nil, fmt.Errorf("setup cache: %w", err)
+	}
+	subsRepo := subs.NewRepository(pool, logger, duplicatePolicy)
+	subsService := subs.NewService(subsRepo, logger, cache, cachePolicy)
+	idempotencyStore := subs.NewIdempotencyRepository(pool, utilsCfg.GetEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour))
+	subsHandler := subs.NewHandler(subsService, logger, idempotencyStore)
 
 	kafkaCfg, err := config.LoadKafkaConfig(logger)
 	if err != nil {
 		return nil, fmt.Errorf("load kafka config: %w", err)
 	}
 
-	maxRetries := 3
-	kafkaConsumer := messaging.NewKafkaConsumer([]string{kafkaCfg.KafkaURL}, kafkaCfg.Topic, kafkaCfg.GroupConsumer, logger, subsHandler, maxRetries)
+	kafkaProducer := messaging.NewKafkaProducer([]string{kafkaCfg.KafkaURL})
+	manager.Add(kafkaProducer)
+	// Сервис добавляется после producer и до потребителей: при остановке он дожидается
+	// фоновой записи заказов, когда новые заказы уже не поступают
+	manager.Add(subsService)
+	// Заказ, не записанный в фоне (политика write-behind), уже подтверждён вызывающему:
+	// он отправляется в DLQ, откуда его можно вернуть командой dlq replay
+	subsService.SetWriteFailureHandler(func(order *models.OrderJSON, err error) {
+		if errors.Is(err, subs.ErrDuplicate) {
+			// Дубликат отклонён политикой дубликатов, как и при синхронной записи
+			return
+		}
+		if err := messaging.SendOrderToDLQ(context.Background(), kafkaProducer, kafkaCfg.DLQTopic, kafkaCfg.Topic, order, "write_behind", err); err != nil {
+			logger.Errorf("main: failed to send order %s to DLQ: %v", order.OrderUID, err)
+		}
+	})
+
+	outboxCfg, err := config.LoadOutboxConfig(logger)
+	if err != nil {
+		return nil, fmt.Errorf("load outbox config: %w", err)
+	}
+	// Relay добавляется после producer, чтобы остановиться раньше него
+	relay := outbox.NewRelay(outbox.NewPostgresStore(pool), kafkaProducer, outbox.Config{
+		Topic:        outboxCfg.Topic,
+		BatchSize:    outboxCfg.BatchSize,
+		PollInterval: outboxCfg.PollInterval,
+		Retention:    outboxCfg.Retention,
+	}, logger)
+	manager.Add(relay)
+
+	// Новые потоки сообщений подключаются регистрацией обработчика в eventRouter
+	eventRouter := messaging.NewOrdersRouter(subsHandler)
+	fallback, err := messaging.ParseUnknownEventPolicy(kafkaCfg.UnknownEventPolicy, logger)
+	if err != nil {
+		return nil, fmt.Errorf("parse unknown event policy: %w", err)
+	}
+	eventRouter.SetFallback(fallback)
+
+	retryTiers := make([]messaging.RetryTier, 0, len(kafkaCfg.RetryTiers))
+	for _, tier := range kafkaCfg.RetryTiers {
+		retryTiers = append(retryTiers, messaging.RetryTier{Topic: tier.Topic, Delay: tier.Delay})
+	}
+	consumerCfg := messaging.ConsumerConfig{
+		Brokers:    []string{kafkaCfg.KafkaURL},
+		Topics:     kafkaCfg.Topics,
+		GroupID:    kafkaCfg.GroupConsumer,
+		MaxRetries: kafkaCfg.MaxRetries,
+		Backoff: messaging.Backoff{
+			Initial:    kafkaCfg.RetryInitialInterval,
+			Max:        kafkaCfg.RetryMaxInterval,
+			Multiplier: kafkaCfg.RetryMultiplier,
+			Jitter:     kafkaCfg.RetryJitter,
+			MaxElapsed: kafkaCfg.RetryMaxElapsed,
+		},
+		RetryTiers: retryTiers,
+		Router:     eventRouter,
+		DLQTopic:   kafkaCfg.DLQTopic,
+
+		Workers:         kafkaCfg.Workers,
+		WorkerQueueSize: kafkaCfg.WorkerQueueSize,
+		BatchSize:       kafkaCfg.BatchSize,
+		BatchTimeout:    kafkaCfg.BatchTimeout,
+		StatsInterval:   kafkaCfg.StatsInterval,
+	}
+	kafkaConsumer := messaging.NewKafkaConsumer(consumerCfg, logger, subsHandler, kafkaProducer)
 	manager.Add(kafkaConsumer)
+	retryConsumers := make([]messaging.Consumer, 0, len(retryTiers))
+	for i := range retryTiers {
+		retryConsumer := messaging.NewKafkaConsumer(consumerCfg.ForRetryTier(i), logger, subsHandler, kafkaProducer)
+		manager.Add(retryConsumer)
+		retryConsumers = append(retryConsumers, retryConsumer)
+	}
 
-	server := router.NewServer(subsHandler, logger)
+	checks := setupHealth(logger, manager, dbHandler, kafkaConsumer, subsService, cache)
+	server := router.NewServer(subsHandler, checks, logger)
 	manager.Add(server)
+	grpcServer := grpcapi.NewServer(subsService, logger)
+	manager.Add(grpcServer)
 
 	return &Application{
-		DBHandler:   dbHandler,
-		subsService: subsService,
-		subsHandler: subsHandler,
-		server:      server,
-		consumer:    kafkaConsumer,
-		PostgresCfg: postgresCfg,
-		kafkaCfg:    kafkaCfg,
+		DBHandler:      dbHandler,
+		subsService:    subsService,
+		subsHandler:    subsHandler,
+		server:         server,
+		grpcServer:     grpcServer,
+		consumer:       kafkaConsumer,
+		retryConsumers: retryConsumers,
+		relay:          relay,
+		PostgresCfg:    postgresCfg,
+		kafkaCfg:       kafkaCfg,
+		cacheCfg:       cacheCfg,
 	}, nil
 
 }
 
+// setupHealth регистрирует проверки готовности: PostgreSQL, Kafka и кэш.
+// После начала остановки manager сервис отвечает на /readyz как не готовый.
+func setupHealth(logger *logrus.Logger, manager *closer.Manager, db *database.HandlerDB,
+	consumer messaging.Consumer, service *subs.Service, cache subs.Cache) *health.Health {
+	manager.SetDrainDelay(utilsCfg.GetEnvDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second))
+
+	checks := health.NewHealth(logger, manager.ShuttingDown)
+	checks.Add("postgres", health.Ping(db.Ping))
+	checks.Add("kafka", func(ctx context.Context) health.Component {
+		component := health.FromError(consumer.Ping(ctx))
+		component.Details = map[string]any{"running": consumer.Running(), "lag": consumer.Lag()}
+		return component
+	})
+	checks.Add("cache", func(ctx context.Context) health.Component {
+		progress := service.WarmUpProgress()
+		component := health.Component{
+			Status:  health.StatusUp,
+			Details: map[string]any{"warm_up": progress.Status, "loaded": progress.Loaded},
+		}
+		if progress.Status == subs.WarmUpWarming {
+			component.Status = subs.WarmUpWarming
+		}
+		if pinger, ok := cache.(health.Pinger); ok {
+			if err := pinger.Ping(ctx); err != nil {
+				component.Status = health.StatusDown
+				component.Error = err.Error()
+			}
+		}
+		return component
+	})
+	return checks
+}
+
+func setupCache(cfg *config.CacheConfig, logger *logrus.Logger, manager *closer.Manager) (subs.Cache, error) {
+	cacheOpts := subs.CacheOptions{
+		MaxEntries: cfg.MaxEntries,
+		MaxBytes:   cfg.MaxBytes,
+		TTL:        cfg.TTL,
+		StaleTTL:   cfg.StaleTTL,
+		MissingTTL: cfg.MissingTTL,
+	}
+	switch cfg.Backend {
+	case "memory":
+		return subs.NewLRUCache(cacheOpts, logger), nil
+	case "redis":
+		opts, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse redis url: %w", err)
+		}
+		client := redis.NewClient(opts)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			_ = client.Close()
+			return nil, fmt.Errorf("ping redis: %w", err)
+		}
+
+		localOpts := cacheOpts
+		localOpts.TTL = cfg.LocalTTL
+		local := subs.NewLRUCache(localOpts, logger)
+		cache := subs.NewRedisCache(client, cacheOpts, local, logger)
+		manager.Add(cache)
+		logger.Infof("main.setupCache: [REDIS] connected to %s", opts.Addr)
+		return cache, nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q: expected memory or redis", cfg.Backend)
+	}
+}
+
 func setupLogger() *logrus.Logger {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
@@ -113,21 +316,32 @@ func setupLogger() *logrus.Logger {
 	return logger
 }
 
-func setupDatabase(URL string, logger *logrus.Logger) (*pgx.Conn, *database.HandlerDB, error) {
+func setupDatabase(cfg *config.PostgresConfig, logger *logrus.Logger) (*pgxpool.Pool, *database.HandlerDB, error) {
+	poolCfg, err := pgxpool.ParseConfig(cfg.URL())
+	if err != nil {
+		return nil, nil, fmt.Errorf("main.setupDatabase: failed to parse database URL: %w", err)
+	}
+	poolCfg.MinConns = cfg.MinConns
+	poolCfg.MaxConns = cfg.MaxConns
+	poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	poolCfg.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
 
-	conn, err := pgx.Connect(context.Background(), URL)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
 		return nil, nil, fmt.Errorf("main.setupDatabase: failed to connect to database: %w", err)
 	}
-	if err := conn.Ping(context.Background()); err != nil {
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, nil, fmt.Errorf("main.setupDatabase: database ping failed: %w", err)
 	}
-	logger.Infof("main: [PGX]: Connected")
+	logger.Infof("main: [PGX]: Pool connected (min=%d, max=%d)", cfg.MinConns, cfg.MaxConns)
 
-	handlerDB := database.NewHandlerDB(conn, logger)
-	if err := handlerDB.CreateTables(context.Background(), conn); err != nil {
-		return nil, nil, fmt.Errorf("main.setupDatabase: failed to create tables: %w", err)
-	}
-	logger.Info("main.setupDatabase: Database connection established and tables created")
-	return conn, handlerDB, nil
+	handlerDB := database.NewHandlerDB(pool, logger)
+	logger.Info("main.setupDatabase: Database connection established")
+	return pool, handlerDB, nil
 }
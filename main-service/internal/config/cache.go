@@ -0,0 +1,64 @@
+package config
+
+import (
+	"orders/pkg/config"
+	"path/filepath"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+// CacheConfig содержит настройки кэша заказов
+type CacheConfig struct {
+	// Backend — реализация кэша: memory (локальный LRU) или redis (общий для реплик)
+	Backend string
+	// Policy — обновление кэша при записи заказа: invalidate, write-through или write-behind
+	Policy string
+	TTL    time.Duration
+	// MaxEntries — максимальное число записей; 0 — без ограничения
+	MaxEntries int
+	// MaxBytes — примерный максимальный объём записей в памяти; 0 — без ограничения
+	MaxBytes int64
+	// StaleTTL — сколько после TTL заказ отдаётся из кэша, пока он обновляется из БД в фоне
+	StaleTTL time.Duration
+	// MissingTTL — время, на которое запоминается отсутствие заказа в БД; 0 — не запоминать
+	MissingTTL time.Duration
+
+	// WarmUpLimit — сколько самых новых заказов загружать при запуске; 0 — пока есть место
+	WarmUpLimit int
+	// WarmUpWindow — загружать только заказы, созданные за это время; 0 — без ограничения
+	WarmUpWindow time.Duration
+	// WarmUpPageSize — число заказов, читаемых из БД за один запрос при прогреве
+	WarmUpPageSize int
+
+	// RedisURL — адрес Redis для Backend=redis
+	RedisURL string
+	// LocalTTL — время жизни записей локального кэша перед Redis
+	LocalTTL time.Duration
+}
+
+// LoadCacheConfig загружает конфигурацию кэша из переменных окружения
+func LoadCacheConfig(logger *logrus.Logger) (*CacheConfig, error) {
+	envPath := filepath.Join("configs", ".env")
+	if err := godotenv.Load(envPath); err != nil {
+		logger.Errorf("config.LoadCacheConfig: %v", err)
+	}
+	config := &CacheConfig{
+		Backend:    config.GetEnv("CACHE_BACKEND", "memory"),
+		Policy:     config.GetEnv("CACHE_POLICY", "write-through"),
+		TTL:        config.GetEnvDuration("CACHE_TTL", 2*time.Minute),
+		MaxEntries: config.GetEnvInt("CACHE_MAX_ENTRIES", 10000),
+		MaxBytes:   int64(config.GetEnvInt("CACHE_MAX_BYTES", 64<<20)),
+		StaleTTL:   config.GetEnvDuration("CACHE_STALE_TTL", 30*time.Second),
+		MissingTTL: config.GetEnvDuration("CACHE_MISSING_TTL", 10*time.Second),
+
+		WarmUpLimit:    config.GetEnvInt("CACHE_WARMUP_LIMIT", 10000),
+		WarmUpWindow:   config.GetEnvDuration("CACHE_WARMUP_WINDOW", 0),
+		WarmUpPageSize: config.GetEnvInt("CACHE_WARMUP_PAGE_SIZE", 500),
+
+		RedisURL: config.GetEnv("REDIS_URL", "redis://redis:6379/0"),
+		LocalTTL: config.GetEnvDuration("CACHE_LOCAL_TTL", 30*time.Second),
+	}
+	return config, nil
+}
@@ -2,19 +2,57 @@
 package config
 
 import (
+	"fmt"
 	"orders/pkg/config"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 )
 
+// RetryTierConfig — retry-топик и задержка перед повторной обработкой сообщений из него
+type RetryTierConfig struct {
+	Topic string
+	Delay time.Duration
+}
+
 // KafkaConfig содержит конфигурацию для подключения к Kafka
 type KafkaConfig struct {
-	KafkaURL      string
-	Topic         string
+	KafkaURL string
+	Topic    string
+	// Topics — все читаемые топики; по умолчанию только Topic
+	Topics        []string
 	GroupConsumer string
-	Logger        *logrus.Logger
+	DLQTopic      string
+	// DLQReplayGroup — группа потребителей, читающая DLQ при реплее
+	DLQReplayGroup string
+	// Workers — число параллельных обработчиков сообщений
+	Workers int
+	// WorkerQueueSize — размер очереди каждого обработчика
+	WorkerQueueSize int
+	// BatchSize — максимальное число заказов, сохраняемых одной транзакцией
+	BatchSize int
+	// BatchTimeout — максимальное время накопления пачки
+	BatchTimeout time.Duration
+	// StatsInterval — период сбора статистики чтения для метрик перебалансировок
+	StatsInterval time.Duration
+	// MaxRetries — число попыток обработки сообщения внутри потребителя, включая первую
+	MaxRetries int
+	// RetryInitialInterval, RetryMaxInterval, RetryMultiplier и RetryJitter задают
+	// экспоненциальные паузы между попытками со случайным отклонением
+	RetryInitialInterval time.Duration
+	RetryMaxInterval     time.Duration
+	RetryMultiplier      float64
+	RetryJitter          float64
+	// RetryMaxElapsed — максимальное время попыток внутри потребителя
+	RetryMaxElapsed time.Duration
+	// RetryTiers — retry-топики <Topic>.retry-<задержка> по возрастанию задержки
+	RetryTiers []RetryTierConfig
+	// UnknownEventPolicy — что делать с сообщением, для типа которого нет обработчика: dlq или skip
+	UnknownEventPolicy string
+	Logger             *logrus.Logger
 }
 
 // LoadKafkaConfig загружает конфигурацию Kafka из переменных окружения
@@ -24,10 +62,61 @@ func LoadKafkaConfig(logger *logrus.Logger) (*KafkaConfig, error) {
 		logger.Errorf("config.LoadPostgresConfig: %v", err)
 		// return nil, fmt.Errorf("config.LoadPostgresConfig: %w", err)
 	}
+	topic := config.GetEnv("TEST_TOPIC", "test_topic")
+	groupConsumer := config.GetEnv("GROUP_ID", "test_group")
+	retryTiers, err := parseRetryTiers(topic, config.GetEnv("KAFKA_RETRY_TIERS", "5s,1m"))
+	if err != nil {
+		return nil, fmt.Errorf("config.LoadKafkaConfig: %w", err)
+	}
 	config := &KafkaConfig{
-		KafkaURL:      config.GetEnv("KAFKA_URL", "kafka:9092"),
-		Topic:         config.GetEnv("TEST_TOPIC", "test_topic"),
-		GroupConsumer: config.GetEnv("GROUP_ID", "test_group"),
+		KafkaURL:       config.GetEnv("KAFKA_URL", "kafka:9092"),
+		Topic:          topic,
+		Topics:         splitList(config.GetEnv("KAFKA_TOPICS", topic)),
+		GroupConsumer:  groupConsumer,
+		DLQTopic:       config.GetEnv("DLQ_TOPIC", topic+".dlq"),
+		DLQReplayGroup: config.GetEnv("DLQ_REPLAY_GROUP_ID", groupConsumer+"-dlq-replay"),
+
+		Workers:         config.GetEnvInt("KAFKA_WORKERS", 4),
+		WorkerQueueSize: config.GetEnvInt("KAFKA_WORKER_QUEUE_SIZE", 64),
+		BatchSize:       config.GetEnvInt("KAFKA_BATCH_SIZE", 50),
+		BatchTimeout:    config.GetEnvDuration("KAFKA_BATCH_TIMEOUT", 200*time.Millisecond),
+		StatsInterval:   config.GetEnvDuration("KAFKA_STATS_INTERVAL", 15*time.Second),
+
+		MaxRetries:           config.GetEnvInt("KAFKA_MAX_RETRIES", 3),
+		RetryInitialInterval: config.GetEnvDuration("KAFKA_RETRY_INITIAL_INTERVAL", 500*time.Millisecond),
+		RetryMaxInterval:     config.GetEnvDuration("KAFKA_RETRY_MAX_INTERVAL", 10*time.Second),
+		RetryMultiplier:      config.GetEnvFloat("KAFKA_RETRY_MULTIPLIER", 2),
+		RetryJitter:          config.GetEnvFloat("KAFKA_RETRY_JITTER", 0.2),
+		RetryMaxElapsed:      config.GetEnvDuration("KAFKA_RETRY_MAX_ELAPSED", 30*time.Second),
+
+		RetryTiers: retryTiers,
+
+		UnknownEventPolicy: config.GetEnv("KAFKA_UNKNOWN_EVENT_POLICY", "dlq"),
 	}
 	return config, nil
 }
+
+// parseRetryTiers разбирает список задержек retry-топиков вида "5s,1m";
+// топик каждого уровня называется <topic>.retry-<задержка>
+func parseRetryTiers(topic, value string) ([]RetryTierConfig, error) {
+	var tiers []RetryTierConfig
+	for _, delay := range splitList(value) {
+		d, err := time.ParseDuration(delay)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid KAFKA_RETRY_TIERS delay %q", delay)
+		}
+		tiers = append(tiers, RetryTierConfig{Topic: topic + ".retry-" + delay, Delay: d})
+	}
+	return tiers, nil
+}
+
+// splitList разбирает список через запятую, пропуская пустые элементы
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"orders/pkg/config"
+	"path/filepath"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+)
+
+// OutboxConfig содержит настройки публикации доменных событий из outbox
+type OutboxConfig struct {
+	// Topic — топик Kafka, в который публикуются события заказов
+	Topic string
+	// BatchSize — максимальное число событий, публикуемых за один проход
+	BatchSize int
+	// PollInterval — пауза между проверками outbox, когда новых событий нет
+	PollInterval time.Duration
+	// Retention — сколько хранятся опубликованные события перед удалением
+	Retention time.Duration
+}
+
+// LoadOutboxConfig загружает конфигурацию outbox из переменных окружения
+func LoadOutboxConfig(logger *logrus.Logger) (*OutboxConfig, error) {
+	envPath := filepath.Join("configs", ".env")
+	if err := godotenv.Load(envPath); err != nil {
+		logger.Errorf("config.LoadOutboxConfig: %v", err)
+	}
+	config := &OutboxConfig{
+		Topic:        config.GetEnv("OUTBOX_TOPIC", "orders.events"),
+		BatchSize:    config.GetEnvInt("OUTBOX_BATCH_SIZE", 100),
+		PollInterval: config.GetEnvDuration("OUTBOX_POLL_INTERVAL", time.Second),
+		Retention:    config.GetEnvDuration("OUTBOX_RETENTION", 24*time.Hour),
+	}
+	return config, nil
+}
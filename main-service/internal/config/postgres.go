@@ -1,8 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"orders/pkg/config"
 	"path/filepath"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -15,6 +17,26 @@ type PostgresConfig struct {
 	Name     string
 	User     string
 	Password string
+
+	// Параметры пула соединений
+	MinConns          int32
+	MaxConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	ConnectTimeout    time.Duration
+
+	// AutoMigrate включает применение миграций схемы при старте сервиса
+	AutoMigrate bool
+
+	// DuplicatePolicy — поведение при записи заказа с уже существующим UID:
+	// skip-if-identical, reject или upsert
+	DuplicatePolicy string
+}
+
+// URL возвращает строку подключения к PostgreSQL
+func (c *PostgresConfig) URL() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s", c.User, c.Password, c.Host, c.Port, c.Name)
 }
 
 // LoadPostgresConfig загружает конфигурацию PostgreSQL из переменных окружения
@@ -30,6 +52,17 @@ func LoadPostgresConfig(logger *logrus.Logger) (*PostgresConfig, error) {
 		Name:     config.GetEnv("DB_NAME", "Orders"),
 		User:     config.GetEnv("DB_USER", "postgres"),
 		Password: config.GetEnv("DB_PASSWORD", "password"),
+
+		MinConns:          int32(config.GetEnvInt("DB_MIN_CONNS", 2)),
+		MaxConns:          int32(config.GetEnvInt("DB_MAX_CONNS", 10)),
+		MaxConnLifetime:   config.GetEnvDuration("DB_MAX_CONN_LIFETIME", time.Hour),
+		MaxConnIdleTime:   config.GetEnvDuration("DB_MAX_CONN_IDLE_TIME", 30*time.Minute),
+		HealthCheckPeriod: config.GetEnvDuration("DB_HEALTH_CHECK_PERIOD", time.Minute),
+		ConnectTimeout:    config.GetEnvDuration("DB_CONNECT_TIMEOUT", 5*time.Second),
+
+		AutoMigrate: config.GetEnvBool("DB_AUTO_MIGRATE", true),
+
+		DuplicatePolicy: config.GetEnv("ORDER_DUPLICATE_POLICY", "skip-if-identical"),
 	}
 	return config, nil
 }
@@ -0,0 +1,53 @@
+// Package database предоставляет управление пулом соединений и миграции схемы базы данных
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+// HandlerDB обрабатывает операции с базой данных
+type HandlerDB struct {
+	pool   *pgxpool.Pool
+	logger *logrus.Logger
+	name   string
+}
+
+// NewHandlerDB создает новый экземпляр HandlerDB
+func NewHandlerDB(pool *pgxpool.Pool, logger *logrus.Logger) *HandlerDB {
+	return &HandlerDB{
+		pool:   pool,
+		logger: logger,
+		name:   "database",
+	}
+}
+
+// Migrate применяет все неприменённые миграции схемы
+func (h *HandlerDB) Migrate(ctx context.Context) error {
+	migrator, err := NewMigrator(h.pool, h.logger)
+	if err != nil {
+		return fmt.Errorf("HandlerDB.Migrate: %w", err)
+	}
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("HandlerDB.Migrate: %w", err)
+	}
+	h.logger.Infof("HandlerDB.Migrate: %d migrations applied", applied)
+	return nil
+}
+
+// Ping проверяет соединение с базой данных
+func (h *HandlerDB) Ping(ctx context.Context) error {
+	return h.pool.Ping(ctx)
+}
+
+func (h *HandlerDB) Name() string { return h.name }
+
+// Close закрывает пул соединений
+func (h *HandlerDB) Close(_ context.Context) error {
+	h.pool.Close()
+	return nil
+}
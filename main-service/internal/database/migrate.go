@@ -0,0 +1,284 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationLockID — ключ advisory lock, под которым выполняются миграции
+const migrationLockID int64 = 7_345_901_221
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`
+
+var errInvalidMigrationName = errors.New("invalid migration file name")
+
+// Migration описывает одну версию схемы с SQL для наката и отката
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus описывает состояние миграции в базе данных
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator применяет встроенные в бинарник миграции схемы
+type Migrator struct {
+	pool       *pgxpool.Pool
+	logger     *logrus.Logger
+	migrations []Migration
+}
+
+// NewMigrator создает новый экземпляр Migrator со встроенными миграциями
+func NewMigrator(pool *pgxpool.Pool, logger *logrus.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("NewMigrator: %w", err)
+	}
+	return &Migrator{
+		pool:       pool,
+		logger:     logger,
+		migrations: migrations,
+	}, nil
+}
+
+// Up применяет все неприменённые миграции и возвращает их количество
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			m.logger.Infof("Migrator.Up: applying %04d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Up,
+				`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
+				migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	if err != nil {
+		return applied, fmt.Errorf("Migrator.Up: %w", err)
+	}
+	return applied, nil
+}
+
+// Down откатывает steps последних применённых миграций и возвращает их количество
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+			m.logger.Infof("Migrator.Down: reverting %04d_%s", migration.Version, migration.Name)
+			if err := m.apply(ctx, conn, migration.Down,
+				`DELETE FROM schema_migrations WHERE version = $1`,
+				migration.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	if err != nil {
+		return reverted, fmt.Errorf("Migrator.Down: %w", err)
+	}
+	return reverted, nil
+}
+
+// Status возвращает список известных миграций с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			appliedAt, ok := versions[migration.Version]
+			statuses = append(statuses, MigrationStatus{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Migrator.Status: %w", err)
+	}
+	return statuses, nil
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock,
+// чтобы несколько реплик не применяли миграции одновременно
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire advisory lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			m.logger.Errorf("Migrator: failed to release advisory lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, script, bookkeeping string, args ...any) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			m.logger.Errorf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("read schema_migrations: %w", err)
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// loadMigrations читает файлы вида 0001_name.up.sql / 0001_name.down.sql из dir
+// и возвращает миграции, отсортированные по версии
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		version, name, direction, err := parseMigrationName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %04d has different names: %q and %q", version, migration.Name, name)
+		}
+		if direction == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func parseMigrationName(fileName string) (int64, string, string, error) {
+	base, found := strings.CutSuffix(fileName, ".sql")
+	if !found {
+		return 0, "", "", fmt.Errorf("%w: %s", errInvalidMigrationName, fileName)
+	}
+	ext := path.Ext(base)
+	direction := strings.TrimPrefix(ext, ".")
+	if direction != "up" && direction != "down" {
+		return 0, "", "", fmt.Errorf("%w: %s", errInvalidMigrationName, fileName)
+	}
+	versionStr, name, found := strings.Cut(strings.TrimSuffix(base, ext), "_")
+	if !found || name == "" {
+		return 0, "", "", fmt.Errorf("%w: %s", errInvalidMigrationName, fileName)
+	}
+	version, err := strconv.ParseInt(versionStr, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, "", "", fmt.Errorf("%w: %s", errInvalidMigrationName, fileName)
+	}
+	return version, name, direction, nil
+}
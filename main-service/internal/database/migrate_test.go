@@ -0,0 +1,57 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLoadMigrations_Embedded тестирует, что встроенные миграции парсятся и идут по порядку
+func TestLoadMigrations_Embedded(t *testing.T) {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, migration := range migrations {
+		assert.Equal(t, int64(i+1), migration.Version, "migration versions must be sequential")
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+}
+
+// TestLoadMigrations_Sorted тестирует сортировку и сопоставление up/down файлов
+func TestLoadMigrations_Sorted(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_status.up.sql":   {Data: []byte("ALTER TABLE orders ADD COLUMN status TEXT;")},
+		"m/0002_add_status.down.sql": {Data: []byte("ALTER TABLE orders DROP COLUMN status;")},
+		"m/0001_init.up.sql":         {Data: []byte("CREATE TABLE orders ();")},
+		"m/0001_init.down.sql":       {Data: []byte("DROP TABLE orders;")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "init", migrations[0].Name)
+	assert.Equal(t, "add_status", migrations[1].Name)
+	assert.Equal(t, "ALTER TABLE orders DROP COLUMN status;", migrations[1].Down)
+}
+
+// TestLoadMigrations_Invalid тестирует отказ на некорректных именах и миграциях без отката
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"no direction": {"m/0001_init.sql": {Data: []byte("SELECT 1;")}},
+		"no version":   {"m/init.up.sql": {Data: []byte("SELECT 1;")}},
+		"missing down": {"m/0001_init.up.sql": {Data: []byte("SELECT 1;")}},
+	}
+
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadMigrations(fsys, "m")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_items_nm_id;
+DROP INDEX IF EXISTS idx_items_chrt_id;
+DROP INDEX IF EXISTS idx_items_track_number;
+DROP INDEX IF EXISTS idx_orders_customer_id;
+DROP INDEX IF EXISTS idx_orders_date_created;
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_orders_date_created ON orders (date_created DESC, order_uid DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders (customer_id);
+CREATE INDEX IF NOT EXISTS idx_items_track_number ON items (track_number);
+CREATE INDEX IF NOT EXISTS idx_items_chrt_id ON items (chrt_id);
+CREATE INDEX IF NOT EXISTS idx_items_nm_id ON items (nm_id);
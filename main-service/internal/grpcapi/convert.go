@@ -0,0 +1,32 @@
+package grpcapi
+
+import (
+	ordersv1 "orders/api/orders/v1"
+	"orders/internal/subs"
+	"orders/pkg/models"
+)
+
+// toOrderFilter собирает фильтр списка заказов из запроса; ошибка курсора — ErrValidation
+func toOrderFilter(req *ordersv1.ListOrdersRequest) (models.OrderFilter, error) {
+	filter := models.OrderFilter{
+		CustomerID:      req.GetCustomerId(),
+		DeliveryService: req.GetDeliveryService(),
+		Locale:          req.GetLocale(),
+		Currency:        req.GetCurrency(),
+		Limit:           int(req.GetLimit()),
+	}
+	if req.GetDateFrom() != nil {
+		filter.DateFrom = req.GetDateFrom().AsTime()
+	}
+	if req.GetDateTo() != nil {
+		filter.DateTo = req.GetDateTo().AsTime()
+	}
+	if req.GetCursor() != "" {
+		cursor, err := subs.DecodeCursor(req.GetCursor())
+		if err != nil {
+			return filter, err
+		}
+		filter.Cursor = cursor
+	}
+	return filter, nil
+}
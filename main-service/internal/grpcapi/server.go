@@ -0,0 +1,179 @@
+// Package grpcapi содержит gRPC сервер для чтения заказов
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	ordersv1 "orders/api/orders/v1"
+	"orders/internal/subs"
+	utilsCfg "orders/pkg/config"
+	"orders/pkg/contract"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// streamBuffer — сколько новых заказов может ждать отправки в один поток StreamNewOrders
+const streamBuffer = 64
+
+// Server реализует ordersv1.OrderServiceServer поверх subs.Service
+type Server struct {
+	ordersv1.UnimplementedOrderServiceServer
+
+	grpcServer *grpc.Server
+	service    *subs.Service
+	logger     *logrus.Logger
+	addr       string
+	name       string
+
+	// stopping закрывается при остановке, чтобы завершить бесконечные потоки до GracefulStop
+	stopping  chan struct{}
+	closeOnce sync.Once
+}
+
+// NewServer создает новый gRPC сервер. Порт задаётся переменной GRPC_PORT.
+func NewServer(service *subs.Service, logger *logrus.Logger) *Server {
+	server := &Server{
+		grpcServer: grpc.NewServer(),
+		service:    service,
+		logger:     logger,
+		addr:       fmt.Sprintf(":%s", utilsCfg.GetEnv("GRPC_PORT", "9090")),
+		name:       "grpc server",
+		stopping:   make(chan struct{}),
+	}
+	ordersv1.RegisterOrderServiceServer(server.grpcServer, server)
+	return server
+}
+
+// Run запускает gRPC сервер на порту GRPC_PORT
+func (s *Server) Run() {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.logger.Errorf("Server.Run: failed to listen %s: %v", s.addr, err)
+		return
+	}
+	s.logger.Infof("Server.Run: gRPC server UP on %s", s.addr)
+	if err := s.Serve(lis); err != nil {
+		s.logger.Errorf("Server.Run: gRPC server stopped: %v", err)
+	}
+}
+
+// Serve обслуживает соединения lis до остановки сервера
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpcServer.Serve(lis)
+}
+
+// GetOrder возвращает заказ по UID
+func (s *Server) GetOrder(ctx context.Context, req *ordersv1.GetOrderRequest) (*ordersv1.Order, error) {
+	if req.GetOrderUid() == "" {
+		return nil, status.Error(codes.InvalidArgument, "order_uid is required")
+	}
+	order, err := s.service.GetOrder(ctx, req.GetOrderUid())
+	if err != nil {
+		return nil, s.toStatus("GetOrder", err)
+	}
+	if order == nil {
+		return nil, status.Errorf(codes.NotFound, "order %s not found", req.GetOrderUid())
+	}
+	return contract.ToProto(order), nil
+}
+
+// ListOrders возвращает страницу заказов по фильтру
+func (s *Server) ListOrders(ctx context.Context, req *ordersv1.ListOrdersRequest) (*ordersv1.ListOrdersResponse, error) {
+	filter, err := toOrderFilter(req)
+	if err != nil {
+		return nil, s.toStatus("ListOrders", err)
+	}
+	page, err := s.service.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, s.toStatus("ListOrders", err)
+	}
+
+	response := &ordersv1.ListOrdersResponse{
+		Orders:     make([]*ordersv1.Order, 0, len(page.Orders)),
+		NextCursor: page.NextCursor,
+	}
+	for i := range page.Orders {
+		response.Orders = append(response.Orders, contract.ToProto(&page.Orders[i]))
+	}
+	return response, nil
+}
+
+// StreamNewOrders отправляет клиенту заказы, сохранённые после подписки,
+// пока клиент не отменит вызов или сервер не начнёт остановку.
+// Заголовки ответа отправляются сразу после подписки: получив их, клиент не пропустит новые заказы.
+func (s *Server) StreamNewOrders(req *ordersv1.StreamNewOrdersRequest, stream grpc.ServerStreamingServer[ordersv1.Order]) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	sub := s.service.SubscribeNewOrders(ctx, subs.SubscriptionFilter{
+		DeliveryService: req.GetDeliveryService(),
+		CustomerID:      req.GetCustomerId(),
+	}, streamBuffer)
+	orders := sub.Orders()
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case order, ok := <-orders:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(contract.ToProto(order)); err != nil {
+				return err
+			}
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// toStatus переводит ошибку сервиса в статус gRPC
+func (s *Server) toStatus(method string, err error) error {
+	switch {
+	case errors.Is(err, subs.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, subs.ErrValidation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, subs.ErrTransient):
+		s.logger.Warnf("Server.%s: temporary failure: %v", method, err)
+		return status.Error(codes.Unavailable, "service temporarily unavailable")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		s.logger.Errorf("Server.%s: %v", method, err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func (s *Server) Name() string { return s.name }
+
+// Close завершает потоки StreamNewOrders и дожидается остальных вызовов;
+// если ctx истекает раньше, соединения закрываются принудительно
+func (s *Server) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() { close(s.stopping) })
+
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,167 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	ordersv1 "orders/api/orders/v1"
+	"orders/internal/subs"
+	"orders/mocks"
+	"orders/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testEnv struct {
+	server  *Server
+	client  ordersv1.OrderServiceClient
+	service *subs.Service
+	repo    *mocks.OrderRepository
+	cache   *mocks.Cache
+}
+
+// newTestEnv поднимает сервер на bufconn и возвращает клиента к нему
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	repo := &mocks.OrderRepository{}
+	cache := &mocks.Cache{}
+	service := subs.NewService(repo, logger, cache, subs.CacheInvalidate)
+	server := NewServer(service, logger)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = server.Close(ctx)
+	})
+	return &testEnv{
+		server:  server,
+		client:  ordersv1.NewOrderServiceClient(conn),
+		service: service,
+		repo:    repo,
+		cache:   cache,
+	}
+}
+
+func testOrder(orderUID string) *models.OrderJSON {
+	return &models.OrderJSON{
+		OrderUID:    orderUID,
+		TrackNumber: "WBILMTESTTRACK",
+		DateCreated: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		Status:      models.StatusPaid,
+		Delivery:    models.Delivery{City: "Kiryat Mozkin"},
+		Payment:     models.Payment{Transaction: orderUID, Amount: 1817},
+		Items:       []models.Item{{ChrtID: 9934930, NmID: 2389212, Price: 453}},
+	}
+}
+
+func TestServer_GetOrder(t *testing.T) {
+	env := newTestEnv(t)
+	order := testOrder("b563feb7b2b84b6test")
+	env.cache.On("Get", order.OrderUID).Return(order, true).Once()
+
+	resp, err := env.client.GetOrder(context.Background(), &ordersv1.GetOrderRequest{OrderUid: order.OrderUID})
+
+	require.NoError(t, err)
+	assert.Equal(t, order.OrderUID, resp.GetOrderUid())
+	assert.Equal(t, "paid", resp.GetStatus())
+	assert.Equal(t, order.DateCreated, resp.GetDateCreated().AsTime())
+	assert.Equal(t, "Kiryat Mozkin", resp.GetDelivery().GetCity())
+	assert.Equal(t, int64(1817), resp.GetPayment().GetAmount())
+	require.Len(t, resp.GetItems(), 1)
+	assert.Equal(t, int64(2389212), resp.GetItems()[0].GetNmId())
+}
+
+func TestServer_GetOrder_NotFound(t *testing.T) {
+	env := newTestEnv(t)
+	env.cache.On("Get", "unknown-order").Return(nil, false).Once()
+	env.cache.On("IsMissing", "unknown-order").Return(true).Once()
+
+	_, err := env.client.GetOrder(context.Background(), &ordersv1.GetOrderRequest{OrderUid: "unknown-order"})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_ListOrders(t *testing.T) {
+	env := newTestEnv(t)
+	orders := []models.OrderJSON{*testOrder("order-uid-0002"), *testOrder("order-uid-0001")}
+	env.repo.On("ListOrders", mock.Anything, mock.MatchedBy(func(f models.OrderFilter) bool {
+		return f.CustomerID == "test" && f.Limit == 2
+	})).Return(orders, nil).Once()
+
+	resp, err := env.client.ListOrders(context.Background(), &ordersv1.ListOrdersRequest{CustomerId: "test", Limit: 1})
+
+	require.NoError(t, err)
+	require.Len(t, resp.GetOrders(), 1)
+	assert.Equal(t, "order-uid-0002", resp.GetOrders()[0].GetOrderUid())
+	assert.NotEmpty(t, resp.GetNextCursor())
+}
+
+func TestServer_ListOrders_InvalidCursor(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.client.ListOrders(context.Background(), &ordersv1.ListOrdersRequest{Cursor: "not a cursor"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	env.repo.AssertNotCalled(t, "ListOrders", mock.Anything, mock.Anything)
+}
+
+func TestServer_StreamNewOrders(t *testing.T) {
+	env := newTestEnv(t)
+	env.repo.On("Create", mock.Anything, mock.Anything).Return(true, nil)
+	env.cache.On("Delete", mock.Anything)
+	env.cache.On("DeleteIndex", mock.Anything)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := env.client.StreamNewOrders(ctx, &ordersv1.StreamNewOrdersRequest{})
+	require.NoError(t, err)
+
+	// Заголовки приходят после того, как сервер оформил подписку
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	require.NoError(t, env.service.Create(ctx, testOrder("b563feb7b2b84b6test")))
+
+	order, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "b563feb7b2b84b6test", order.GetOrderUid())
+}
+
+func TestServer_Close_EndsStreams(t *testing.T) {
+	env := newTestEnv(t)
+
+	stream, err := env.client.StreamNewOrders(context.Background(), &ordersv1.StreamNewOrdersRequest{})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	require.NoError(t, env.server.Close(ctx))
+
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,149 @@
+// Package health содержит проверки живости и готовности сервиса
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// checkTimeout ограничивает время одной проверки готовности
+const checkTimeout = 2 * time.Second
+
+// Состояния компонентов и сервиса
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusReady        = "ready"
+	StatusNotReady     = "not_ready"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Component — результат проверки одной зависимости.
+// Компонент считается готовым только со статусом up.
+type Component struct {
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Report — ответ проверки готовности
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// CheckFunc проверяет одну зависимость сервиса
+type CheckFunc func(ctx context.Context) Component
+
+// FromError возвращает компонент со статусом up или down в зависимости от err
+func FromError(err error) Component {
+	if err != nil {
+		return Component{Status: StatusDown, Error: err.Error()}
+	}
+	return Component{Status: StatusUp}
+}
+
+// Pinger — зависимость, умеющая проверить своё соединение
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping превращает функцию проверки соединения в CheckFunc
+func Ping(ping func(ctx context.Context) error) CheckFunc {
+	return func(ctx context.Context) Component {
+		return FromError(ping(ctx))
+	}
+}
+
+type namedCheck struct {
+	name  string
+	check CheckFunc
+}
+
+// Health выполняет проверки зависимостей и отвечает на /healthz и /readyz
+type Health struct {
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown func() bool
+	logger       *logrus.Logger
+}
+
+// NewHealth создает новый экземпляр Health.
+// shuttingDown сообщает о начале остановки сервиса; после этого сервис не готов.
+func NewHealth(logger *logrus.Logger, shuttingDown func() bool) *Health {
+	return &Health{
+		shuttingDown: shuttingDown,
+		logger:       logger,
+	}
+}
+
+// Add регистрирует проверку зависимости под именем name
+func (h *Health) Add(name string, check CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// Check выполняет все проверки параллельно и возвращает сводный отчёт
+func (h *Health) Check(ctx context.Context) Report {
+	if h.shuttingDown != nil && h.shuttingDown() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	results := make([]Component, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Components: make(map[string]Component, len(checks))}
+	for i, c := range checks {
+		report.Components[c.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusNotReady
+		}
+	}
+	return report
+}
+
+// LivenessFromHTTP отвечает 200, пока процесс способен обслуживать HTTP запросы
+func (h *Health) LivenessFromHTTP(w http.ResponseWriter, _ *http.Request) {
+	h.writeJSON(w, http.StatusOK, Report{Status: StatusUp})
+}
+
+// ReadinessFromHTTP отвечает 200, если все зависимости готовы, и 503 с отчётом по компонентам иначе
+func (h *Health) ReadinessFromHTTP(w http.ResponseWriter, r *http.Request) {
+	report := h.Check(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusReady {
+		status = http.StatusServiceUnavailable
+		h.logger.Debugf("Health.ReadinessFromHTTP: %s: %+v", report.Status, report.Components)
+	}
+	h.writeJSON(w, status, report)
+}
+
+func (h *Health) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		h.logger.Errorf("Health.writeJSON: failed to write response: %v", err)
+	}
+}
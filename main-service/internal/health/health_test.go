@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestHealth(shuttingDown func() bool) *Health {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewHealth(logger, shuttingDown)
+}
+
+func readiness(t *testing.T, h *Health) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ReadinessFromHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return rec.Code, report
+}
+
+func TestHealth_Ready(t *testing.T) {
+	h := newTestHealth(nil)
+	h.Add("postgres", Ping(func(context.Context) error { return nil }))
+
+	code, report := readiness(t, h)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusReady, report.Status)
+	assert.Equal(t, StatusUp, report.Components["postgres"].Status)
+}
+
+func TestHealth_ComponentDown(t *testing.T) {
+	h := newTestHealth(nil)
+	h.Add("postgres", Ping(func(context.Context) error { return nil }))
+	h.Add("kafka", Ping(func(context.Context) error { return errors.New("connection refused") }))
+
+	code, report := readiness(t, h)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusNotReady, report.Status)
+	assert.Equal(t, StatusUp, report.Components["postgres"].Status)
+	assert.Equal(t, StatusDown, report.Components["kafka"].Status)
+	assert.Equal(t, "connection refused", report.Components["kafka"].Error)
+}
+
+func TestHealth_ShuttingDown(t *testing.T) {
+	h := newTestHealth(func() bool { return true })
+	h.Add("postgres", Ping(func(context.Context) error { return nil }))
+
+	code, report := readiness(t, h)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusShuttingDown, report.Status)
+
+	rec := httptest.NewRecorder()
+	h.LivenessFromHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -15,6 +15,22 @@ var (
 		[]string{"status"}, // success, error
 	)
 
+	OrderDuplicatesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "order_duplicates_total",
+			Help: "Total orders received with an already stored UID",
+		},
+		[]string{"policy", "result"}, // result: skipped, rejected, replaced
+	)
+
+	OrderStatusTransitionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "order_status_transitions_total",
+			Help: "Total order status transitions",
+		},
+		[]string{"from", "to"},
+	)
+
 	OrderProcessingDuration = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "order_processing_duration_seconds",
@@ -24,6 +40,13 @@ var (
 		[]string{"operation"},
 	)
 
+	OrderWriteBehindPending = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "order_write_behind_pending",
+			Help: "Number of orders accepted with the write-behind cache policy and not yet written to the database",
+		},
+	)
+
 	// Cache
 	OrdersInCache = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -32,6 +55,123 @@ var (
 		},
 	)
 
+	CacheEntries = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_entries",
+			Help: "Number of entries (orders and index keys) currently in cache",
+		},
+	)
+
+	CacheBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_bytes",
+			Help: "Estimated memory used by cache entries",
+		},
+	)
+
+	CacheMaxEntries = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_max_entries",
+			Help: "Configured cache entries limit (0 - unlimited)",
+		},
+	)
+
+	CacheMaxBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_max_bytes",
+			Help: "Configured cache memory limit (0 - unlimited)",
+		},
+	)
+
+	CacheTTLSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_ttl_seconds",
+			Help: "Configured cache entry TTL",
+		},
+	)
+
+	CacheEvictionsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_evictions_total",
+			Help: "Total entries removed from cache",
+		},
+		[]string{"reason"}, // capacity, expired
+	)
+
+	CacheInvalidationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_invalidations_total",
+			Help: "Total order invalidations applied to the cache",
+		},
+		[]string{"source"}, // local, pubsub
+	)
+
+	CacheErrorsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_errors_total",
+			Help: "Total failed operations with the external cache",
+		},
+		[]string{"operation"},
+	)
+
+	CacheLookupsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_lookups_total",
+			Help: "Total order lookups in cache by result",
+		},
+		[]string{"result"}, // hit, stale, negative, miss
+	)
+
+	CacheSharedLoadsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_shared_loads_total",
+			Help: "Total order lookups that shared a database load with concurrent requests",
+		},
+	)
+
+	CacheWarmUpInProgress = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_in_progress",
+			Help: "Whether cache warm-up is running (1) or not (0)",
+		},
+	)
+
+	CacheWarmUpLoadedOrders = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_loaded_orders",
+			Help: "Number of orders loaded into cache by the current or last warm-up",
+		},
+	)
+
+	CacheWarmUpDurationSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cache_warmup_duration_seconds",
+			Help: "Duration of the last cache warm-up",
+		},
+	)
+
+	CacheWarmUpFailuresTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cache_warmup_failures_total",
+			Help: "Total cache warm-ups stopped by an error",
+		},
+	)
+
+	// Subscriptions
+	OrderSubscribers = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "order_subscribers",
+			Help: "Number of active new order subscriptions (SSE and gRPC streams)",
+		},
+	)
+
+	OrderSubscriberDropsTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "order_subscriber_drops_total",
+			Help: "Total new orders not delivered to a subscriber because its buffer was full",
+		},
+	)
+
 	// Kafka
 	KafkaMessagesTotal = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -58,4 +198,125 @@ var (
 		},
 		[]string{"topic", "status"},
 	)
+
+	KafkaInFlightMessages = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_in_flight_messages",
+			Help: "Number of fetched Kafka messages whose offsets are not committed yet",
+		},
+		[]string{"topic"},
+	)
+
+	KafkaBatchSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "kafka_batch_size",
+			Help:    "Number of Kafka messages persisted in one batch",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"topic"},
+	)
+
+	KafkaDeadLetterTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_dead_letter_messages_total",
+			Help: "Total messages sent to the dead-letter topic",
+		},
+		[]string{"topic", "error_type", "status"}, // status: published, failed
+	)
+
+	KafkaRetriedMessagesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_retried_messages_total",
+			Help: "Total messages moved to a retry topic after a temporary error",
+		},
+		[]string{"topic", "retry_topic"},
+	)
+
+	KafkaConsumerLag = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_lag",
+			Help: "Messages between the committed offset and the high watermark of an assigned partition",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	KafkaConsumerOffset = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_offset",
+			Help: "Offset of the last message fetched from an assigned partition",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	KafkaConsumerCommittedOffset = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_committed_offset",
+			Help: "Next offset to consume committed for an assigned partition",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	KafkaConsumerHighWatermark = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_high_watermark",
+			Help: "High watermark of an assigned partition reported with the last fetch",
+		},
+		[]string{"group", "topic", "partition"},
+	)
+
+	KafkaConsumerAssignedPartitions = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kafka_consumer_assigned_partitions",
+			Help: "Number of partitions the consumer has fetched from since the last rebalance",
+		},
+		[]string{"group"},
+	)
+
+	KafkaConsumerRebalancesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_rebalances_total",
+			Help: "Total consumer group generations joined by the reader",
+		},
+		[]string{"group"},
+	)
+
+	KafkaConsumerAssignmentChangesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_consumer_assignment_changes_total",
+			Help: "Total partitions assigned to or revoked from the consumer",
+		},
+		[]string{"group", "change"}, // change: assigned, revoked
+	)
+
+	KafkaDLQReplayedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "kafka_dlq_replayed_messages_total",
+			Help: "Total messages replayed from the dead-letter topic",
+		},
+		[]string{"dlq_topic", "status"},
+	)
+
+	// Outbox
+	OutboxEventsPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_events_published_total",
+			Help: "Total domain events published from the outbox",
+		},
+		[]string{"event_type"},
+	)
+
+	OutboxPublishFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_failures_total",
+			Help: "Total failed attempts to publish an outbox event",
+		},
+		[]string{"event_type"},
+	)
+
+	OutboxRelayLagSeconds = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_relay_lag_seconds",
+			Help: "Age of the oldest unpublished outbox event, 0 when the outbox is drained",
+		},
+	)
 )
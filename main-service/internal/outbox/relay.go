@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"orders/internal/metrics"
+	"orders/kafka/messaging"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HeaderEventID — заголовок с идентификатором события; по нему потребители
+// отбрасывают повторы, возможные при доставке at-least-once
+const HeaderEventID = "x-event-id"
+
+// cleanupInterval — период удаления опубликованных событий старше Config.Retention
+const cleanupInterval = time.Minute
+
+// Config содержит настройки Relay
+type Config struct {
+	Topic        string
+	BatchSize    int
+	PollInterval time.Duration
+	Retention    time.Duration
+}
+
+// Relay переносит события из outbox в Kafka. Событие отмечается опубликованным только
+// после подтверждения от Kafka, поэтому при сбое между отправкой и отметкой оно будет
+// отправлено повторно: доставка at-least-once.
+type Relay struct {
+	store    Store
+	producer messaging.Producer
+	cfg      Config
+	logger   *logrus.Logger
+	name     string
+
+	started atomic.Bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewRelay создает новый экземпляр Relay
+func NewRelay(store Store, producer messaging.Producer, cfg Config, logger *logrus.Logger) *Relay {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	return &Relay{
+		store:    store,
+		producer: producer,
+		cfg:      cfg,
+		logger:   logger,
+		name:     "outbox relay",
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run публикует события, пока ctx не отменён или Relay не закрыт.
+// Если пачка заполнена целиком, следующая читается без паузы.
+func (r *Relay) Run(ctx context.Context) {
+	r.started.Store(true)
+	defer close(r.done)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-r.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	r.logger.Infof("Relay.Run: publishing outbox events to %s", r.cfg.Topic)
+	var lastCleanup time.Time
+	for {
+		processed, err := r.store.ProcessBatch(ctx, r.cfg.BatchSize, r.publish)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Warnf("Relay.Run: %v", err)
+		}
+		if processed == 0 {
+			metrics.OutboxRelayLagSeconds.Set(0)
+		}
+
+		if r.cfg.Retention > 0 && time.Since(lastCleanup) >= cleanupInterval {
+			lastCleanup = time.Now()
+			r.cleanup(ctx)
+		}
+
+		if err == nil && processed == r.cfg.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-time.After(r.cfg.PollInterval):
+		case <-ctx.Done():
+			r.logger.Info("Relay.Run: stopped")
+			return
+		}
+	}
+}
+
+// publish отправляет события по порядку и останавливается на первой ошибке,
+// чтобы события одного заказа не обгоняли друг друга
+func (r *Relay) publish(ctx context.Context, events []Event) (int, error) {
+	metrics.OutboxRelayLagSeconds.Set(time.Since(events[0].CreatedAt).Seconds())
+	for i, event := range events {
+		msg := messaging.Message{
+			Key:   []byte(event.AggregateID),
+			Value: event.Payload,
+			Headers: map[string]string{
+				messaging.HeaderEventType: event.Type,
+				HeaderEventID:             strconv.FormatInt(event.ID, 10),
+			},
+		}
+		if err := r.producer.ProduceMessage(ctx, r.cfg.Topic, msg); err != nil {
+			metrics.OutboxPublishFailuresTotal.WithLabelValues(event.Type).Inc()
+			return i, fmt.Errorf("publish event %d (%s, attempt %d): %w", event.ID, event.Type, event.Attempts+1, err)
+		}
+		metrics.OutboxEventsPublishedTotal.WithLabelValues(event.Type).Inc()
+	}
+	return len(events), nil
+}
+
+// cleanup удаляет опубликованные события старше Config.Retention
+func (r *Relay) cleanup(ctx context.Context) {
+	deleted, err := r.store.DeletePublishedBefore(ctx, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			r.logger.Warnf("Relay.cleanup: %v", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		r.logger.Debugf("Relay.cleanup: deleted %d published events", deleted)
+	}
+}
+
+// Close останавливает публикацию и ждёт завершения текущей пачки
+func (r *Relay) Close(ctx context.Context) error {
+	close(r.stop)
+	if r.started.Load() {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (r *Relay) Name() string { return r.name }
@@ -0,0 +1,155 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"orders/kafka/messaging"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore — Store в памяти с той же семантикой отметок, что у PostgresStore
+type memStore struct {
+	mu     sync.Mutex
+	events []Event
+	// published — идентификаторы опубликованных событий
+	published map[int64]bool
+}
+
+func newMemStore(events ...Event) *memStore {
+	return &memStore{events: events, published: make(map[int64]bool)}
+}
+
+func (s *memStore) ProcessBatch(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var batch []Event
+	for _, event := range s.events {
+		if !s.published[event.ID] && len(batch) < limit {
+			batch = append(batch, event)
+		}
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	n, err := publish(ctx, batch)
+	for _, event := range batch[:n] {
+		s.published[event.ID] = true
+	}
+	if err != nil && n < len(batch) {
+		for i := range s.events {
+			if s.events[i].ID == batch[n].ID {
+				s.events[i].Attempts++
+			}
+		}
+	}
+	return len(batch), err
+}
+
+func (s *memStore) DeletePublishedBefore(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *memStore) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events) - len(s.published)
+}
+
+// fakeProducer запоминает отправленные сообщения; первые failures отправок завершаются ошибкой
+type fakeProducer struct {
+	mu       sync.Mutex
+	sent     []messaging.Message
+	failures int
+}
+
+func (p *fakeProducer) ProduceMessage(_ context.Context, _ string, msg messaging.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("broker unavailable")
+	}
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+func (p *fakeProducer) messages() []messaging.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]messaging.Message(nil), p.sent...)
+}
+
+func (p *fakeProducer) Close(context.Context) error { return nil }
+func (p *fakeProducer) Name() string                { return "fake producer" }
+
+func testEvents(n int) []Event {
+	events := make([]Event, 0, n)
+	for i := 1; i <= n; i++ {
+		events = append(events, Event{
+			ID:          int64(i),
+			AggregateID: "order-uid",
+			Type:        "order.updated",
+			Payload:     []byte(`{}`),
+			CreatedAt:   time.Now(),
+		})
+	}
+	return events
+}
+
+func newTestRelay(store Store, producer messaging.Producer) *Relay {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return NewRelay(store, producer, Config{
+		Topic:        "orders.events",
+		BatchSize:    2,
+		PollInterval: 10 * time.Millisecond,
+	}, logger)
+}
+
+func TestRelay_PublishesInOrder(t *testing.T) {
+	store := newMemStore(testEvents(5)...)
+	producer := &fakeProducer{}
+	relay := newTestRelay(store, producer)
+
+	go relay.Run(context.Background())
+	require.Eventually(t, func() bool { return store.pending() == 0 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, relay.Close(context.Background()))
+
+	sent := producer.messages()
+	require.Len(t, sent, 5)
+	for i, msg := range sent {
+		assert.Equal(t, "order-uid", string(msg.Key))
+		assert.Equal(t, "order.updated", msg.Headers[messaging.HeaderEventType])
+		assert.Equal(t, strconv.Itoa(i+1), msg.Headers[HeaderEventID])
+	}
+}
+
+func TestRelay_RetriesFailedEvent(t *testing.T) {
+	store := newMemStore(testEvents(3)...)
+	producer := &fakeProducer{failures: 2}
+	relay := newTestRelay(store, producer)
+
+	go relay.Run(context.Background())
+	require.Eventually(t, func() bool { return store.pending() == 0 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, relay.Close(context.Background()))
+
+	// Неудачная отправка не пропускает событие и не меняет порядок
+	sent := producer.messages()
+	require.Len(t, sent, 3)
+	assert.Equal(t, "1", sent[0].Headers[HeaderEventID])
+	assert.Equal(t, "3", sent[2].Headers[HeaderEventID])
+	assert.Equal(t, 2, store.events[0].Attempts)
+}
+
+func TestRelay_CloseWithoutRun(t *testing.T) {
+	relay := newTestRelay(newMemStore(), &fakeProducer{})
+
+	assert.NoError(t, relay.Close(context.Background()))
+}
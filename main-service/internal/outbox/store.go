@@ -0,0 +1,139 @@
+// Package outbox публикует доменные события заказов, записанные в таблицу outbox
+// в одной транзакции с изменением заказа
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// outboxLockID — ключ advisory lock, под которым события публикует только одна реплика,
+// чтобы события одного заказа не уходили в Kafka в разном порядке
+const outboxLockID int64 = 7_345_901_222
+
+// maxErrorLength — сколько символов ошибки публикации сохраняется в last_error
+const maxErrorLength = 1024
+
+// Event — событие из outbox, ожидающее публикации
+type Event struct {
+	ID          int64
+	AggregateID string
+	Type        string
+	Payload     []byte
+	CreatedAt   time.Time
+	Attempts    int
+}
+
+// PublishFunc публикует события по порядку и возвращает, сколько из них опубликовано
+// до первой ошибки
+type PublishFunc func(ctx context.Context, events []Event) (int, error)
+
+// Store хранит события outbox
+type Store interface {
+	// ProcessBatch передаёт publish до limit самых старых неопубликованных событий и отмечает
+	// опубликованные. Возвращает число переданных событий; 0 — если событий нет
+	// или их публикует другая реплика.
+	ProcessBatch(ctx context.Context, limit int, publish PublishFunc) (int, error)
+	// DeletePublishedBefore удаляет события, опубликованные раньше t
+	DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// PostgresStore реализует Store в PostgreSQL
+type PostgresStore struct {
+	client *pgxpool.Pool
+}
+
+// NewPostgresStore создает новый экземпляр PostgresStore
+func NewPostgresStore(client *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{client: client}
+}
+
+// ProcessBatch публикует пачку событий внутри транзакции, удерживающей advisory lock
+func (s *PostgresStore) ProcessBatch(ctx context.Context, limit int, publish PublishFunc) (int, error) {
+	tx, err := s.client.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("PostgresStore.ProcessBatch: begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(context.WithoutCancel(ctx)) }()
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockID).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("PostgresStore.ProcessBatch: acquire lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	events, err := fetchUnpublished(ctx, tx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("PostgresStore.ProcessBatch: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	published, publishErr := publish(ctx, events)
+	// Отметки сохраняются, даже если ctx отменён посреди публикации:
+	// иначе уже отправленные события уйдут повторно после перезапуска
+	writeCtx := context.WithoutCancel(ctx)
+	if published > 0 {
+		ids := make([]int64, 0, published)
+		for _, event := range events[:published] {
+			ids = append(ids, event.ID)
+		}
+		if _, err := tx.Exec(writeCtx,
+			`UPDATE outbox SET published_at = now(), attempts = attempts + 1 WHERE id = ANY($1)`, ids); err != nil {
+			return 0, fmt.Errorf("PostgresStore.ProcessBatch: mark published: %w", err)
+		}
+	}
+	if publishErr != nil && published < len(events) {
+		message := publishErr.Error()
+		if len(message) > maxErrorLength {
+			message = message[:maxErrorLength]
+		}
+		if _, err := tx.Exec(writeCtx,
+			`UPDATE outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1`,
+			events[published].ID, message); err != nil {
+			return 0, fmt.Errorf("PostgresStore.ProcessBatch: mark failed: %w", err)
+		}
+	}
+	if err := tx.Commit(writeCtx); err != nil {
+		return 0, fmt.Errorf("PostgresStore.ProcessBatch: commit: %w", err)
+	}
+	return len(events), publishErr
+}
+
+func fetchUnpublished(ctx context.Context, tx pgx.Tx, limit int) ([]Event, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT id, aggregate_id, event_type, payload, created_at, attempts
+		FROM outbox
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("select events: %w", err)
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Event, error) {
+		var event Event
+		err := row.Scan(&event.ID, &event.AggregateID, &event.Type, &event.Payload, &event.CreatedAt, &event.Attempts)
+		return event, err
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("scan events: %w", err)
+	}
+	return events, nil
+}
+
+// DeletePublishedBefore удаляет события, опубликованные раньше t
+func (s *PostgresStore) DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	tag, err := s.client.Exec(ctx, `DELETE FROM outbox WHERE published_at < $1`, t)
+	if err != nil {
+		return 0, fmt.Errorf("PostgresStore.DeletePublishedBefore: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,88 @@
+package subs
+
+import (
+	"context"
+	"orders/internal/metrics"
+	"orders/pkg/models"
+	"sync"
+	"sync/atomic"
+)
+
+// SubscriptionFilter отбирает новые заказы для подписчика; пустые поля не фильтруют
+type SubscriptionFilter struct {
+	DeliveryService string
+	CustomerID      string
+}
+
+func (f SubscriptionFilter) matches(order *models.OrderJSON) bool {
+	return (f.DeliveryService == "" || f.DeliveryService == order.DeliveryService) &&
+		(f.CustomerID == "" || f.CustomerID == order.CustomerID)
+}
+
+// Subscription — подписка на заказы, сохранённые этой репликой.
+// Заказы из канала общие для всех подписчиков и не должны изменяться.
+type Subscription struct {
+	orders  chan *models.OrderJSON
+	filter  SubscriptionFilter
+	dropped atomic.Int64
+}
+
+// Orders возвращает канал новых заказов; он закрывается после отмены контекста подписки
+func (s *Subscription) Orders() <-chan *models.OrderJSON {
+	return s.orders
+}
+
+// TakeDropped возвращает число заказов, пропущенных с прошлого вызова из-за заполненного буфера
+func (s *Subscription) TakeDropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// orderBroker рассылает сохранённые заказы подписчикам этой реплики.
+// Публикация не блокирует запись заказов: если подписчик не успевает читать и его буфер
+// заполнен, заказ для него пропускается и учитывается в TakeDropped.
+type orderBroker struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+func newOrderBroker() *orderBroker {
+	return &orderBroker{subscribers: make(map[*Subscription]struct{})}
+}
+
+// subscribe оформляет подписку, которая действует до отмены ctx
+func (b *orderBroker) subscribe(ctx context.Context, filter SubscriptionFilter, buffer int) *Subscription {
+	sub := &Subscription{
+		orders: make(chan *models.OrderJSON, buffer),
+		filter: filter,
+	}
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+	metrics.OrderSubscribers.Inc()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, sub)
+		close(sub.orders)
+		b.mu.Unlock()
+		metrics.OrderSubscribers.Dec()
+	}()
+	return sub
+}
+
+func (b *orderBroker) publish(order *models.OrderJSON) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subscribers {
+		if !sub.filter.matches(order) {
+			continue
+		}
+		select {
+		case sub.orders <- order:
+		default:
+			sub.dropped.Add(1)
+			metrics.OrderSubscriberDropsTotal.Inc()
+		}
+	}
+}
@@ -1,109 +1,393 @@
 package subs
 
 import (
+	"container/list"
 	"orders/internal/metrics"
 	"orders/pkg/models"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
+	"unsafe"
 
 	"github.com/sirupsen/logrus"
 )
 
+const timeTickerCleanCache = 1 * time.Minute
+
+// Префиксы ключей индекса для поиска по вторичным идентификаторам
 const (
-	timeTTL              = 2 * time.Minute
-	timeTickerCleanCache = 1 * time.Minute
+	trackNumberKeyPrefix = "track:"
+	chrtIDKeyPrefix      = "chrt:"
+	nmIDKeyPrefix        = "nm:"
+	// missingKeyPrefix — ключ отметки о том, что заказа с таким UID нет в БД
+	missingKeyPrefix = "missing:"
 )
 
+func trackNumberKey(trackNumber string) string { return trackNumberKeyPrefix + trackNumber }
+func chrtIDKey(chrtID int64) string            { return chrtIDKeyPrefix + strconv.FormatInt(chrtID, 10) }
+func nmIDKey(nmID int64) string                { return nmIDKeyPrefix + strconv.FormatInt(nmID, 10) }
+func missingKey(orderUID string) string        { return missingKeyPrefix + orderUID }
+
+func itemIndexKey(chrtID, nmID int64) string {
+	switch {
+	case chrtID != 0 && nmID != 0:
+		return chrtIDKey(chrtID) + "/" + nmIDKey(nmID)
+	case chrtID != 0:
+		return chrtIDKey(chrtID)
+	default:
+		return nmIDKey(nmID)
+	}
+}
+
+// orderIndexKeys возвращает ключи индекса, по которым может быть найден заказ:
+// трек-номер и chrt_id, nm_id и их пара для каждого товара
+func orderIndexKeys(order *models.OrderJSON) []string {
+	keys := []string{trackNumberKey(order.TrackNumber)}
+	seen := map[string]bool{keys[0]: true}
+	for _, item := range order.Items {
+		for _, key := range []string{itemIndexKey(item.ChrtID, 0), itemIndexKey(0, item.NmID), itemIndexKey(item.ChrtID, item.NmID)} {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
 // Cache определяет интерфейс для работы с кэшем заказов
 type Cache interface {
 	Get(key string) (*models.OrderJSON, bool)
 	Set(key string, value *models.OrderJSON)
 	Delete(orderUID string)
-	WarmUpCache(orders []models.OrderJSON) error
+	// GetStale возвращает заказ, даже если его TTL истёк, но не прошло ещё StaleTTL
+	GetStale(key string) (*models.OrderJSON, bool)
+	// SetMissing запоминает, что заказа с таким UID нет, на MissingTTL
+	SetMissing(orderUID string)
+	IsMissing(orderUID string) bool
+	// WarmUpCache загружает заказы в кэш, пока в нём есть место, и возвращает число загруженных
+	WarmUpCache(orders []models.OrderJSON) (int, error)
+	GetIndex(key string) ([]string, bool)
+	SetIndex(key string, orderUIDs []string)
+	// DeleteIndex удаляет записи индекса, например после записи заказа с этими идентификаторами
+	DeleteIndex(keys []string)
 }
 
-// InMemoryCache реализует Cache с хранением в памяти
-type InMemoryCache struct {
-	data   map[string]cacheEntry
-	mu     sync.RWMutex
-	ttl    time.Duration
-	logger *logrus.Logger
+// CacheOptions содержит ограничения и времена жизни записей кэша
+type CacheOptions struct {
+	// MaxEntries ограничивает общее число записей (заказов и элементов индекса); 0 — без ограничения
+	MaxEntries int
+	// MaxBytes — примерный максимальный объём записей в памяти; 0 — без ограничения
+	MaxBytes int64
+	// TTL — время, в течение которого заказ считается актуальным
+	TTL time.Duration
+	// StaleTTL — сколько ещё после TTL заказ можно отдавать, пока он обновляется в фоне
+	StaleTTL time.Duration
+	// MissingTTL — время жизни отметки об отсутствующем заказе
+	MissingTTL time.Duration
+}
+
+// LRUCache реализует Cache с ограничением по числу записей и по объёму памяти.
+// При переполнении вытесняются давно не использовавшиеся записи (LRU),
+// устаревшие по TTL записи удаляются фоновой очисткой.
+type LRUCache struct {
+	mu     sync.Mutex
+	lru    *list.List // от недавно использованных к давно не использовавшимся
+	data   map[string]*list.Element
+	index  map[string]*list.Element
+	bytes  int64
+	orders int
+
+	maxEntries int
+	maxBytes   int64
+	ttl        time.Duration
+	staleTTL   time.Duration
+	missingTTL time.Duration
+	logger     *logrus.Logger
 }
 
+// cacheEntry — запись кэша: заказ или элемент индекса вторичных идентификаторов
+// (track_number, chrt_id, nm_id), связывающий их с UID заказов.
+// Отметки об отсутствующих заказах хранятся как элементы индекса без UID.
 type cacheEntry struct {
+	key       string
+	isIndex   bool
 	order     models.OrderJSON
+	orderUIDs []string
+	size      int64
 	expiresAt time.Time
 }
 
-// NewInMemoryCache создает новый экземпляр InMemoryCache
-func NewInMemoryCache(logger *logrus.Logger) *InMemoryCache {
-	cache := &InMemoryCache{
-		data:   make(map[string]cacheEntry),
-		ttl:    timeTTL,
-		logger: logger,
+// NewLRUCache создает новый экземпляр LRUCache
+func NewLRUCache(opts CacheOptions, logger *logrus.Logger) *LRUCache {
+	cache := &LRUCache{
+		lru:        list.New(),
+		data:       make(map[string]*list.Element),
+		index:      make(map[string]*list.Element),
+		maxEntries: opts.MaxEntries,
+		maxBytes:   opts.MaxBytes,
+		ttl:        opts.TTL,
+		staleTTL:   opts.StaleTTL,
+		missingTTL: opts.MissingTTL,
+		logger:     logger,
 	}
+	metrics.CacheTTLSeconds.Set(opts.TTL.Seconds())
+	metrics.CacheMaxEntries.Set(float64(opts.MaxEntries))
+	metrics.CacheMaxBytes.Set(float64(opts.MaxBytes))
 	go cache.startCacheCleaner()
 	return cache
 }
 
-// Get возвращает заказ из кэша по ключу
-func (c *InMemoryCache) Get(key string) (*models.OrderJSON, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// Get возвращает копию заказа из кэша по ключу; её можно изменять, не затрагивая кэш
+func (c *LRUCache) Get(key string) (*models.OrderJSON, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.lookup(c.data, key, 0)
+	if !found {
+		return nil, false
+	}
+	order := cloneOrder(&entry.order)
+	return &order, true
+}
 
-	entry, found := c.data[key]
-	if !found || time.Now().After(entry.expiresAt) {
+// GetStale возвращает заказ из кэша, даже если его TTL истёк не более StaleTTL назад
+func (c *LRUCache) GetStale(key string) (*models.OrderJSON, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.lookup(c.data, key, c.staleTTL)
+	if !found {
 		return nil, false
 	}
-	return &entry.order, true
+	order := cloneOrder(&entry.order)
+	return &order, true
 }
 
 // Set сохраняет заказ в кэше
-func (c *InMemoryCache) Set(key string, value *models.OrderJSON) {
+func (c *LRUCache) Set(key string, value *models.OrderJSON) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.data[key] = cacheEntry{
-		order:     *value,
+	c.put(&cacheEntry{
+		key:       key,
+		order:     cloneOrder(value),
+		size:      orderSize(value),
 		expiresAt: time.Now().Add(c.ttl),
+	})
+	if elem, exists := c.index[missingKey(key)]; exists {
+		c.remove(elem)
 	}
+	c.evict()
+	c.updateMetrics()
+}
 
-	metrics.OrdersInCache.Set(float64(len(c.data)))
+// Delete удаляет заказ и отметку о его отсутствии из кэша
+func (c *LRUCache) Delete(orderUID string) {
+	if !c.invalidate(orderUID) {
+		c.logger.Debugf("Cache entry to delete is not present: %s", orderUID)
+		return
+	}
+	c.logger.Infof("Cache invalidated for order: %s", orderUID)
 }
 
-// Delete удаляет заказ из кэша
-func (c *InMemoryCache) Delete(orderUID string) {
+// invalidate удаляет заказ и отметку о его отсутствии из кэша, если они там есть
+func (c *LRUCache) invalidate(orderUID string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	_, existed := c.data[orderUID]
-	if !existed {
-		c.logger.Warnf("Attempt to delete non-existent cache entry: %s", orderUID)
+	removed := false
+	if elem, existed := c.data[orderUID]; existed {
+		c.remove(elem)
+		removed = true
+	}
+	if elem, existed := c.index[missingKey(orderUID)]; existed {
+		c.remove(elem)
+		removed = true
+	}
+	if removed {
+		c.updateMetrics()
+	}
+	return removed
+}
+
+// SetMissing запоминает, что заказа с таким UID нет в БД
+func (c *LRUCache) SetMissing(orderUID string) {
+	if c.missingTTL <= 0 {
 		return
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	delete(c.data, orderUID)
-	metrics.OrdersInCache.Set(float64(len(c.data)))
-	c.logger.Infof("Cache invalidated for order: %s", orderUID)
+	key := missingKey(orderUID)
+	c.put(&cacheEntry{
+		key:       key,
+		isIndex:   true,
+		size:      indexSize(key, nil),
+		expiresAt: time.Now().Add(c.missingTTL),
+	})
+	c.evict()
+	c.updateMetrics()
 }
 
-// WarmUpCache предзагружает заказы в кэш
-func (c *InMemoryCache) WarmUpCache(orders []models.OrderJSON) error {
+// IsMissing сообщает, отмечен ли заказ как отсутствующий
+func (c *LRUCache) IsMissing(orderUID string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	now := time.Now()
-	for _, order := range orders {
-		c.data[order.OrderUID] = cacheEntry{
-			order:     order,
-			expiresAt: now.Add(c.ttl),
+	_, found := c.lookup(c.index, missingKey(orderUID), 0)
+	return found
+}
+
+// GetIndex возвращает UID заказов по ключу вторичного идентификатора
+func (c *LRUCache) GetIndex(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.lookup(c.index, key, 0)
+	if !found {
+		return nil, false
+	}
+	return entry.orderUIDs, true
+}
+
+// DeleteIndex удаляет записи индекса по ключам
+func (c *LRUCache) DeleteIndex(keys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := false
+	for _, key := range keys {
+		if elem, existed := c.index[key]; existed {
+			c.remove(elem)
+			removed = true
+		}
+	}
+	if removed {
+		c.updateMetrics()
+	}
+}
+
+// SetIndex сохраняет UID заказов по ключу вторичного идентификатора
+func (c *LRUCache) SetIndex(key string, orderUIDs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.put(&cacheEntry{
+		key:       key,
+		isIndex:   true,
+		orderUIDs: orderUIDs,
+		size:      indexSize(key, orderUIDs),
+		expiresAt: time.Now().Add(c.ttl),
+	})
+	c.evict()
+	c.updateMetrics()
+}
+
+// WarmUpCache предзагружает заказы в кэш, пока в нём есть место, и возвращает их число.
+// Заказы должны идти от самых востребованных (новых) к менее востребованным:
+// прогрев не вытесняет уже загруженные им записи, в том числе при загрузке по страницам.
+func (c *LRUCache) WarmUpCache(orders []models.OrderJSON) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	loaded := 0
+	for i := range orders {
+		order := &orders[i]
+		size := orderSize(order)
+		if _, exists := c.data[order.OrderUID]; !exists && !c.fits(size) {
+			break
 		}
+		// PushBack, чтобы первые (самые новые) заказы оставались ближе к началу списка LRU
+		c.put(&cacheEntry{
+			key:       order.OrderUID,
+			order:     cloneOrder(order),
+			size:      size,
+			expiresAt: expiresAt,
+		})
+		c.lru.MoveToBack(c.data[order.OrderUID])
+		loaded++
+	}
+	c.updateMetrics()
+	c.logger.Debugf("Cache warmed up with %d of %d orders", loaded, len(orders))
+	return loaded, nil
+}
+
+// cloneOrder копирует заказ вместе с товарами: кэш не должен делить срез Items
+// с вызывающим, который может изменить заказ после Set или Get
+func cloneOrder(order *models.OrderJSON) models.OrderJSON {
+	clone := *order
+	clone.Items = slices.Clone(order.Items)
+	return clone
+}
+
+// lookup возвращает запись, устаревшую не более чем на stale, и отмечает её как недавно использованную
+func (c *LRUCache) lookup(entries map[string]*list.Element, key string, stale time.Duration) (*cacheEntry, bool) {
+	elem, found := entries[key]
+	if !found {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt.Add(stale)) {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// put добавляет или заменяет запись и делает её самой недавно использованной
+func (c *LRUCache) put(entry *cacheEntry) {
+	entries := c.data
+	if entry.isIndex {
+		entries = c.index
+	}
+	if elem, exists := entries[entry.key]; exists {
+		c.remove(elem)
 	}
-	metrics.OrdersInCache.Set(float64(len(c.data)))
-	c.logger.Infof("Cache warmed up with %d orders", len(orders))
-	return nil
+	entries[entry.key] = c.lru.PushFront(entry)
+	c.bytes += entry.size
+	if !entry.isIndex {
+		c.orders++
+	}
+}
+
+func (c *LRUCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	if entry.isIndex {
+		delete(c.index, entry.key)
+	} else {
+		delete(c.data, entry.key)
+		c.orders--
+	}
+	c.bytes -= entry.size
+}
+
+// fits сообщает, поместится ли запись размером size без вытеснения
+func (c *LRUCache) fits(size int64) bool {
+	if c.maxEntries > 0 && c.lru.Len()+1 > c.maxEntries {
+		return false
+	}
+	return c.maxBytes <= 0 || c.bytes+size <= c.maxBytes
+}
+
+// evict вытесняет давно не использовавшиеся записи, пока кэш превышает ограничения
+func (c *LRUCache) evict() {
+	for c.lru.Len() > 1 &&
+		((c.maxEntries > 0 && c.lru.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes)) {
+		c.remove(c.lru.Back())
+		metrics.CacheEvictionsTotal.WithLabelValues("capacity").Inc()
+	}
+}
+
+func (c *LRUCache) updateMetrics() {
+	metrics.OrdersInCache.Set(float64(c.orders))
+	metrics.CacheEntries.Set(float64(c.lru.Len()))
+	metrics.CacheBytes.Set(float64(c.bytes))
 }
-func (c *InMemoryCache) startCacheCleaner() {
+
+func (c *LRUCache) startCacheCleaner() {
 	ticker := time.NewTicker(timeTickerCleanCache)
 	defer ticker.Stop()
 
@@ -112,23 +396,62 @@ func (c *InMemoryCache) startCacheCleaner() {
 	}
 }
 
-func (c *InMemoryCache) cleanExpiredCache() {
+func (c *LRUCache) cleanExpiredCache() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	now := time.Now()
-	cleaned := false
-
-	for orderUID, entry := range c.data {
-		if now.After(entry.expiresAt) {
-			delete(c.data, orderUID)
-			cleaned = true
-			c.logger.Infof("Cache EXPIRED: %s", orderUID)
+	expired := 0
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		entry := elem.Value.(*cacheEntry)
+		// устаревшие заказы хранятся ещё StaleTTL, чтобы их можно было отдать во время обновления
+		removeAt := entry.expiresAt
+		if !entry.isIndex {
+			removeAt = removeAt.Add(c.staleTTL)
+		}
+		if now.After(removeAt) {
+			c.remove(elem)
+			expired++
+			if !entry.isIndex {
+				c.logger.Debugf("Cache EXPIRED: %s", entry.key)
+			}
 		}
+		elem = prev
+	}
+
+	if expired > 0 {
+		metrics.CacheEvictionsTotal.WithLabelValues("expired").Add(float64(expired))
+		c.updateMetrics()
+	}
+}
+
+// Примерные накладные расходы на запись кэша: элемент списка, записи в map, заголовки строк
+const cacheEntryOverhead = int64(unsafe.Sizeof(cacheEntry{}) + unsafe.Sizeof(list.Element{}) + 64)
+
+// orderSize оценивает объём памяти, занимаемый заказом в кэше
+func orderSize(order *models.OrderJSON) int64 {
+	size := cacheEntryOverhead + int64(len(order.OrderUID)*2) // ключ map и поле заказа
+	size += int64(len(order.TrackNumber) + len(order.Entry) + len(order.Locale) + len(order.InternalSignature) +
+		len(order.CustomerID) + len(order.DeliveryService) + len(order.ShardKey) + len(order.OofShard) + len(order.Status))
+
+	d := order.Delivery
+	size += int64(len(d.OrderUID) + len(d.Name) + len(d.Phone) + len(d.Zip) + len(d.City) + len(d.Address) + len(d.Region) + len(d.Email))
+	p := order.Payment
+	size += int64(len(p.Transaction) + len(p.RequestID) + len(p.Currency) + len(p.Provider) + len(p.Bank))
+
+	size += int64(len(order.Items)) * int64(unsafe.Sizeof(models.Item{}))
+	for _, item := range order.Items {
+		size += int64(len(item.TrackNumber) + len(item.RID) + len(item.Name) + len(item.Size) + len(item.Brand))
 	}
+	return size
+}
 
-	// Обновляем метрику только если что-то почистили
-	if cleaned {
-		metrics.OrdersInCache.Set(float64(len(c.data)))
+// indexSize оценивает объём памяти, занимаемый элементом индекса в кэше
+func indexSize(key string, orderUIDs []string) int64 {
+	size := cacheEntryOverhead + int64(len(key)*2)
+	for _, orderUID := range orderUIDs {
+		size += int64(unsafe.Sizeof(orderUID)) + int64(len(orderUID))
 	}
+	return size
 }
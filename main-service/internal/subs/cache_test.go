@@ -0,0 +1,171 @@
+package subs
+
+import (
+	"fmt"
+	"orders/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOrder(orderUID string) models.OrderJSON {
+	return models.OrderJSON{
+		OrderUID:    orderUID,
+		TrackNumber: "TRACK" + orderUID,
+		Items:       []models.Item{{ChrtID: 1, Name: "item"}},
+	}
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 2, TTL: time.Minute}, getTestLogger())
+
+	first, second, third := testOrder("order-1"), testOrder("order-2"), testOrder("order-3")
+	cache.Set(first.OrderUID, &first)
+	cache.Set(second.OrderUID, &second)
+
+	// order-1 становится недавно использованным, вытесняться должен order-2
+	_, found := cache.Get(first.OrderUID)
+	assert.True(t, found)
+
+	cache.Set(third.OrderUID, &third)
+
+	_, found = cache.Get(second.OrderUID)
+	assert.False(t, found)
+	_, found = cache.Get(first.OrderUID)
+	assert.True(t, found)
+	_, found = cache.Get(third.OrderUID)
+	assert.True(t, found)
+}
+
+func TestLRUCache_MaxBytes(t *testing.T) {
+	order := testOrder("order-1")
+	size := orderSize(&order)
+	cache := NewLRUCache(CacheOptions{MaxBytes: 3 * size, TTL: time.Minute}, getTestLogger())
+
+	for i := 1; i <= 5; i++ {
+		order := testOrder(fmt.Sprintf("order-%d", i))
+		cache.Set(order.OrderUID, &order)
+	}
+
+	assert.LessOrEqual(t, cache.bytes, 3*size)
+	_, found := cache.Get("order-1")
+	assert.False(t, found)
+	_, found = cache.Get("order-5")
+	assert.True(t, found)
+}
+
+func TestLRUCache_IndexCountsTowardsLimit(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 2, TTL: time.Minute}, getTestLogger())
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	cache.SetIndex(trackNumberKey("TRACK1"), []string{"order-1"})
+	cache.SetIndex(trackNumberKey("TRACK2"), []string{"order-2"})
+
+	_, found := cache.Get(order.OrderUID)
+	assert.False(t, found)
+	uids, found := cache.GetIndex(trackNumberKey("TRACK2"))
+	assert.True(t, found)
+	assert.Equal(t, []string{"order-2"}, uids)
+}
+
+func TestLRUCache_WarmUpKeepsFirstOrders(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 2, TTL: time.Minute}, getTestLogger())
+
+	orders := []models.OrderJSON{testOrder("newest"), testOrder("newer"), testOrder("oldest")}
+	loaded, err := cache.WarmUpCache(orders)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, loaded)
+	_, found := cache.Get("newest")
+	assert.True(t, found)
+	_, found = cache.Get("newer")
+	assert.True(t, found)
+	_, found = cache.Get("oldest")
+	assert.False(t, found)
+}
+
+func TestLRUCache_Expired(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 10, TTL: time.Millisecond}, getTestLogger())
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	time.Sleep(5 * time.Millisecond)
+
+	_, found := cache.Get(order.OrderUID)
+	assert.False(t, found)
+
+	cache.cleanExpiredCache()
+	assert.Equal(t, 0, cache.lru.Len())
+	assert.Equal(t, int64(0), cache.bytes)
+}
+
+func TestLRUCache_Delete(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 10, TTL: time.Minute}, getTestLogger())
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	cache.Delete(order.OrderUID)
+
+	_, found := cache.Get(order.OrderUID)
+	assert.False(t, found)
+	assert.Equal(t, 0, cache.orders)
+	assert.Equal(t, int64(0), cache.bytes)
+}
+
+// TestLRUCache_CopiesItems тестирует, что изменение заказа, переданного в Set
+// или полученного из Get и GetStale, не меняет заказ в кэше
+func TestLRUCache_CopiesItems(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 10, TTL: time.Minute, StaleTTL: time.Minute}, getTestLogger())
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	order.Items[0].Name = "changed after set"
+
+	got, found := cache.Get(order.OrderUID)
+	assert.True(t, found)
+	assert.Equal(t, "item", got.Items[0].Name)
+	got.Items[0].Name = "changed after get"
+
+	stale, found := cache.GetStale(order.OrderUID)
+	assert.True(t, found)
+	assert.Equal(t, "item", stale.Items[0].Name)
+	stale.Items[0].Name = "changed after get stale"
+
+	got, _ = cache.Get(order.OrderUID)
+	assert.Equal(t, "item", got.Items[0].Name)
+}
+
+func TestLRUCache_Stale(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 10, TTL: time.Millisecond, StaleTTL: time.Minute}, getTestLogger())
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	time.Sleep(5 * time.Millisecond)
+
+	_, found := cache.Get(order.OrderUID)
+	assert.False(t, found)
+	_, found = cache.GetStale(order.OrderUID)
+	assert.True(t, found)
+
+	// устаревшая запись переживает очистку, пока не истёк StaleTTL
+	cache.cleanExpiredCache()
+	assert.Equal(t, 1, cache.orders)
+}
+
+func TestLRUCache_Missing(t *testing.T) {
+	cache := NewLRUCache(CacheOptions{MaxEntries: 10, TTL: time.Minute, MissingTTL: time.Minute}, getTestLogger())
+
+	cache.SetMissing("order-1")
+	assert.True(t, cache.IsMissing("order-1"))
+	assert.False(t, cache.IsMissing("order-2"))
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	assert.False(t, cache.IsMissing("order-1"))
+
+	cache.SetMissing("order-2")
+	cache.Delete("order-2")
+	assert.False(t, cache.IsMissing("order-2"))
+}
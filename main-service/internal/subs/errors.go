@@ -0,0 +1,180 @@
+package subs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"orders/pkg/models"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Ошибки пакета. Вызывающий код различает их через errors.Is,
+// не разбирая текст ошибки.
+var (
+	// ErrDuplicate — заказ с таким UID уже существует
+	ErrDuplicate = errors.New("record already exist")
+	// ErrNotFound — запись не найдена
+	ErrNotFound = errors.New("record not found")
+	// ErrValidation — данные не прошли проверку и не будут приняты при повторе
+	ErrValidation = errors.New("validation failed")
+	// ErrTransient — временная ошибка БД, операцию можно повторить
+	ErrTransient = errors.New("transient database error")
+	// ErrInvalidTransition — переход заказа в запрошенный статус не разрешён
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrIncomplete — у сохранённого заказа нет доставки или оплаты; данные в БД нарушены
+	ErrIncomplete = errors.New("order is incomplete")
+)
+
+// validate потокобезопасен и кэширует разобранные структуры, поэтому создаётся один раз
+var validate = validator.New()
+
+// FieldError описывает ошибку в одном поле заказа
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError содержит список полей, не прошедших проверку.
+// errors.Is(err, ErrValidation) для неё возвращает true.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(parts, "; "))
+}
+
+// Is позволяет сравнивать ValidationError с ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// ValidateOrder проверяет заказ по тегам validate и возвращает *ValidationError
+func ValidateOrder(order *models.OrderJSON) error {
+	if order == nil {
+		return &ValidationError{Fields: []FieldError{{Field: "order", Message: "is empty"}}}
+	}
+	return validateStruct(order)
+}
+
+// ValidateStatusEvent проверяет событие смены статуса и возвращает *ValidationError
+func ValidateStatusEvent(event *models.StatusEvent) error {
+	if event == nil {
+		return &ValidationError{Fields: []FieldError{{Field: "event", Message: "is empty"}}}
+	}
+	return validateStruct(event)
+}
+
+func validateStruct(v any) error {
+	err := validate.Struct(v)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	fields := make([]FieldError, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		message := "failed on the '" + fe.Tag() + "' tag"
+		if fe.Param() != "" {
+			message += " (" + fe.Param() + ")"
+		}
+		fields = append(fields, FieldError{Field: fe.Namespace(), Message: message})
+	}
+	return &ValidationError{Fields: fields}
+}
+
+// DBError — ошибка базы данных, отнесённая к одной из ошибок пакета (Kind).
+// errors.Is работает и с Kind, и с исходной ошибкой драйвера.
+type DBError struct {
+	Kind error
+	// Code — SQLSTATE, если ошибку вернул сервер PostgreSQL
+	Code string
+	Err  error
+}
+
+func (e *DBError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%v (SQLSTATE %s): %v", e.Kind, e.Code, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+func (e *DBError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// classifyError сопоставляет ошибку pgx одной из ошибок пакета.
+// Нераспознанные ошибки возвращаются без изменений и считаются постоянными.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if kind := kindBySQLState(pgErr.Code); kind != nil {
+			return &DBError{Kind: kind, Code: pgErr.Code, Err: err}
+		}
+		return err
+	}
+
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return &DBError{Kind: ErrNotFound, Err: err}
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err), pgconn.SafeToRetry(err):
+		return &DBError{Kind: ErrTransient, Err: err}
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	if errors.As(err, &connectErr) || errors.As(err, &netErr) {
+		return &DBError{Kind: ErrTransient, Err: err}
+	}
+	return err
+}
+
+// kindBySQLState возвращает ошибку пакета для кода SQLSTATE или nil,
+// если код не классифицируется
+func kindBySQLState(code string) error {
+	switch code {
+	case "23505": // unique_violation
+		return ErrDuplicate
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"55P03", // lock_not_available
+		"57014", // query_canceled (statement_timeout)
+		"57P01", // admin_shutdown
+		"57P02", // crash_shutdown
+		"57P03": // cannot_connect_now
+		return ErrTransient
+	}
+
+	if len(code) < 2 {
+		return nil
+	}
+	switch code[:2] {
+	case "08", // connection_exception
+		"53": // insufficient_resources
+		return ErrTransient
+	case "22", // data_exception
+		"23": // integrity_constraint_violation
+		return ErrValidation
+	}
+	return nil
+}
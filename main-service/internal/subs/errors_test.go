@@ -0,0 +1,66 @@
+package subs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orders/pkg/models"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError_SQLState(t *testing.T) {
+	tests := []struct {
+		code string
+		kind error
+	}{
+		{"23505", ErrDuplicate},
+		{"23502", ErrValidation},
+		{"22001", ErrValidation},
+		{"40001", ErrTransient},
+		{"40P01", ErrTransient},
+		{"08006", ErrTransient},
+		{"53300", ErrTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			pgErr := &pgconn.PgError{Code: tt.code}
+			err := classifyError(fmt.Errorf("insert order: %w", pgErr))
+
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, pgErr)
+		})
+	}
+}
+
+func TestClassifyError_Unknown(t *testing.T) {
+	pgErr := &pgconn.PgError{Code: "42P01"}
+	err := classifyError(pgErr)
+
+	assert.Same(t, pgErr, err)
+	for _, kind := range []error{ErrDuplicate, ErrNotFound, ErrValidation, ErrTransient} {
+		assert.NotErrorIs(t, err, kind)
+	}
+}
+
+func TestClassifyError_DriverErrors(t *testing.T) {
+	assert.ErrorIs(t, classifyError(pgx.ErrNoRows), ErrNotFound)
+	assert.ErrorIs(t, classifyError(context.DeadlineExceeded), ErrTransient)
+	assert.NoError(t, classifyError(nil))
+}
+
+func TestValidateOrder(t *testing.T) {
+	err := ValidateOrder(&models.OrderJSON{OrderUID: "short"})
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.NotEmpty(t, validationErr.Fields)
+	assert.Equal(t, "OrderJSON.OrderUID", validationErr.Fields[0].Field)
+}
@@ -0,0 +1,123 @@
+package subs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"orders/pkg/models"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+	cursorSeparator  = "|"
+)
+
+var errInvalidCursor = fmt.Errorf("%w: invalid cursor", ErrValidation)
+
+// EncodeCursor кодирует курсор в непрозрачную строку для клиента
+func EncodeCursor(c models.Cursor) string {
+	raw := c.DateCreated.UTC().Format(time.RFC3339Nano) + cursorSeparator + c.OrderUID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor разбирает строку курсора, полученную от клиента
+func DecodeCursor(s string) (*models.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCursor, err)
+	}
+	dateStr, orderUID, found := strings.Cut(string(raw), cursorSeparator)
+	if !found || orderUID == "" {
+		return nil, errInvalidCursor
+	}
+	dateCreated, err := time.Parse(time.RFC3339Nano, dateStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidCursor, err)
+	}
+	return &models.Cursor{DateCreated: dateCreated, OrderUID: orderUID}, nil
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
+
+// buildListOrdersQuery собирает запрос выборки UID заказов по фильтру
+func buildListOrdersQuery(filter models.OrderFilter) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	addArg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.CustomerID != "" {
+		conditions = append(conditions, "o.customer_id = "+addArg(filter.CustomerID))
+	}
+	if filter.DeliveryService != "" {
+		conditions = append(conditions, "o.delivery_service = "+addArg(filter.DeliveryService))
+	}
+	if filter.Locale != "" {
+		conditions = append(conditions, "o.locale = "+addArg(filter.Locale))
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "p.currency = "+addArg(filter.Currency))
+	}
+	if !filter.DateFrom.IsZero() {
+		conditions = append(conditions, "o.date_created >= "+addArg(filter.DateFrom))
+	}
+	if !filter.DateTo.IsZero() {
+		conditions = append(conditions, "o.date_created < "+addArg(filter.DateTo))
+	}
+	if filter.Cursor != nil {
+		date := addArg(filter.Cursor.DateCreated)
+		uid := addArg(filter.Cursor.OrderUID)
+		conditions = append(conditions, fmt.Sprintf("(o.date_created, o.order_uid) < (%s, %s)", date, uid))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT o.order_uid FROM orders o")
+	if filter.Currency != "" {
+		sb.WriteString(" JOIN payments p ON p.transaction = o.order_uid")
+	}
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conditions, " AND "))
+	}
+	sb.WriteString(" ORDER BY o.date_created DESC, o.order_uid DESC LIMIT ")
+	sb.WriteString(addArg(filter.Limit))
+
+	return sb.String(), args
+}
+
+// buildOrdersByItemQuery собирает запрос выборки UID заказов по chrt_id и/или nm_id товара
+func buildOrdersByItemQuery(chrtID, nmID int64) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if chrtID != 0 {
+		args = append(args, chrtID)
+		conditions = append(conditions, fmt.Sprintf("i.chrt_id = $%d", len(args)))
+	}
+	if nmID != 0 {
+		args = append(args, nmID)
+		conditions = append(conditions, fmt.Sprintf("i.nm_id = $%d", len(args)))
+	}
+	args = append(args, maxListLimit)
+
+	query := fmt.Sprintf(`SELECT o.order_uid FROM orders o
+		WHERE EXISTS (SELECT 1 FROM items i WHERE i.track_number = o.track_number AND %s)
+		ORDER BY o.date_created DESC, o.order_uid DESC LIMIT $%d`,
+		strings.Join(conditions, " AND "), len(args))
+	return query, args
+}
@@ -0,0 +1,60 @@
+package subs
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// orderGenerationStripes — число счётчиков поколений заказов
+const orderGenerationStripes = 256
+
+// orderGenerations отслеживает запись заказов, чтобы загрузка из БД не положила в кэш
+// заказ, прочитанный до записи, которая успела обновить кэш раньше загрузки.
+// Счётчики разделены по хэшу UID: заказы с одинаковым хэшем делят счётчик, и запись
+// одного из них лишь заставляет загрузку другого пропустить кэширование.
+type orderGenerations struct {
+	stripes [orderGenerationStripes]generationStripe
+}
+
+// generationStripe — счётчик поколений группы заказов
+type generationStripe struct {
+	mu         sync.Mutex
+	generation uint64
+}
+
+// stripe возвращает счётчик, отвечающий за заказ
+func (g *orderGenerations) stripe(orderUID string) *generationStripe {
+	h := fnv.New32a()
+	h.Write([]byte(orderUID))
+	return &g.stripes[h.Sum32()%orderGenerationStripes]
+}
+
+// Current возвращает поколение заказа; загрузка запоминает его до запроса к БД
+func (g *orderGenerations) Current(orderUID string) uint64 {
+	stripe := g.stripe(orderUID)
+	stripe.mu.Lock()
+	defer stripe.mu.Unlock()
+	return stripe.generation
+}
+
+// Bump отмечает запись заказа. Вызывается до обновления кэша после записи.
+func (g *orderGenerations) Bump(orderUID string) {
+	stripe := g.stripe(orderUID)
+	stripe.mu.Lock()
+	defer stripe.mu.Unlock()
+	stripe.generation++
+}
+
+// IfCurrent вызывает update, если с поколения generation заказ не записывался, и
+// сообщает, был ли вызов. Bump ждёт завершения update, поэтому обновление кэша
+// после записи всегда следует за обновлением загрузкой.
+func (g *orderGenerations) IfCurrent(orderUID string, generation uint64, update func()) bool {
+	stripe := g.stripe(orderUID)
+	stripe.mu.Lock()
+	defer stripe.mu.Unlock()
+	if stripe.generation != generation {
+		return false
+	}
+	update()
+	return true
+}
@@ -7,8 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"orders/pkg/models"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -18,27 +21,41 @@ import (
 type Handler struct {
 	service *Service
 	logger  *logrus.Logger
+	// idempotency хранит ответы на запросы создания с Idempotency-Key; nil отключает повторы
+	idempotency IdempotencyStore
+	// streamsDone закрывается при остановке сервера и завершает потоки /orders/stream
+	streamsDone  chan struct{}
+	closeStreams sync.Once
 }
 
 // NewHandler создает новый экземпляр Handler
-func NewHandler(service *Service, logger *logrus.Logger) *Handler {
+func NewHandler(service *Service, logger *logrus.Logger, idempotency IdempotencyStore) *Handler {
 	return &Handler{
-		service: service,
-		logger:  logger,
+		service:     service,
+		logger:      logger,
+		idempotency: idempotency,
+		streamsDone: make(chan struct{}),
 	}
 }
 
 // Create создает новый заказ в системе
 func (h *Handler) Create(ctx context.Context, jsonOrder *models.OrderJSON) error {
-	return h.service.Create(ctx, jsonOrder) // can delete handler, but we stay here for future http handling
+	return h.service.Create(ctx, jsonOrder)
+}
+
+// CreateBatch создает пачку заказов и возвращает ошибку для каждого заказа
+func (h *Handler) CreateBatch(ctx context.Context, orders []*models.OrderJSON) ([]error, error) {
+	return h.service.CreateBatch(ctx, orders)
+}
 
+// ChangeStatus применяет событие смены статуса заказа
+func (h *Handler) ChangeStatus(ctx context.Context, event *models.StatusEvent) error {
+	return h.service.ChangeStatus(ctx, event.OrderUID, event.Status, event.Reason)
 }
 
 // GetOrderFromHTTP обрабатывает HTTP запрос для получения заказа
 func (h *Handler) GetOrderFromHTTP(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	setCORSHeaders(w)
 
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusOK)
@@ -86,6 +103,133 @@ func (h *Handler) GetOrderFromHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ListOrdersFromHTTP обрабатывает HTTP запрос для получения страницы заказов
+func (h *Handler) ListOrdersFromHTTP(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		h.logger.Warnf("Handler.ListOrdersFromHTTP: invalid method %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Has("chrt_id") || query.Has("nm_id") {
+		h.listOrdersByItem(w, r, query)
+		return
+	}
+
+	filter, err := parseOrderFilter(query)
+	if err != nil {
+		h.logger.Warnf("Handler.ListOrdersFromHTTP: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.service.ListOrders(r.Context(), filter)
+	if err != nil {
+		h.handleListError(w, "ListOrdersFromHTTP", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, page)
+}
+
+// GetOrderResourceFromHTTP обрабатывает HTTP запросы к вложенным ресурсам заказа
+// /order/{order_uid}/{resource}. Сейчас поддерживается только history.
+func (h *Handler) GetOrderResourceFromHTTP(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		h.logger.Warnf("Handler.GetOrderResourceFromHTTP: invalid method %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.PathValue("resource") != "history" {
+		http.NotFound(w, r)
+		return
+	}
+
+	orderUID := r.PathValue("order_uid")
+	history, err := h.service.GetStatusHistory(r.Context(), orderUID)
+	if err != nil {
+		h.handleGetOrderError(w, err, orderUID)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, history)
+}
+
+// GetOrderByTrackFromHTTP обрабатывает HTTP запрос для получения заказа по трек-номеру
+func (h *Handler) GetOrderByTrackFromHTTP(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		h.logger.Warnf("Handler.GetOrderByTrackFromHTTP: invalid method %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	trackNumber := getParamFromPath(r.URL.Path)
+	if trackNumber == "" {
+		h.logger.Warn("Handler.GetOrderByTrackFromHTTP: empty track number")
+		http.Error(w, "Track number is required", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.service.GetOrderByTrackNumber(r.Context(), trackNumber)
+	if err != nil {
+		h.handleGetOrderError(w, err, trackNumber)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, order)
+}
+
+func (h *Handler) listOrdersByItem(w http.ResponseWriter, r *http.Request, query url.Values) {
+	chrtID, err := parseItemID(query, "chrt_id")
+	if err != nil {
+		h.logger.Warnf("Handler.listOrdersByItem: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	nmID, err := parseItemID(query, "nm_id")
+	if err != nil {
+		h.logger.Warnf("Handler.listOrdersByItem: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if chrtID == 0 && nmID == 0 {
+		h.logger.Warn("Handler.listOrdersByItem: neither chrt_id nor nm_id is set")
+		http.Error(w, "chrt_id or nm_id is required", http.StatusBadRequest)
+		return
+	}
+
+	orders, err := h.service.GetOrdersByItem(r.Context(), chrtID, nmID)
+	if err != nil {
+		h.handleListError(w, "listOrdersByItem", err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, models.OrderPage{Orders: orders})
+}
+
 // GetOrder возвращает заказ по его UID
 func (h *Handler) GetOrder(orderUID string) {
 	order, err := h.service.GetOrder(context.Background(), orderUID)
@@ -95,15 +239,113 @@ func (h *Handler) GetOrder(orderUID string) {
 	}
 	fmt.Println(order)
 }
+
 func (h *Handler) handleGetOrderError(w http.ResponseWriter, err error, orderUID string) {
-	if errors.Is(err, errNotFound) || strings.Contains(err.Error(), "not found") {
+	switch {
+	case errors.Is(err, ErrNotFound):
 		h.logger.Warnf("Handler.handleGetOrderError: order %s not found: %v", orderUID, err)
 		http.Error(w, fmt.Sprintf("Order %s not found", orderUID), http.StatusNotFound)
+	case errors.Is(err, ErrValidation):
+		h.logger.Warnf("Handler.handleGetOrderError: invalid request for order %s: %v", orderUID, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrTransient):
+		h.logger.Warnf("Handler.handleGetOrderError: temporary failure for order %s: %v", orderUID, err)
+		http.Error(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
+	default:
+		h.logger.Errorf("Handler.handleGetOrderError: failed to get order %s: %v", orderUID, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// handleListError отвечает на ошибку выборки списка заказов
+func (h *Handler) handleListError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrValidation):
+		h.logger.Warnf("Handler.%s: %v", op, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrTransient):
+		h.logger.Warnf("Handler.%s: temporary failure: %v", op, err)
+		http.Error(w, "Service temporarily unavailable", http.StatusServiceUnavailable)
+	default:
+		h.logger.Errorf("Handler.%s: failed to list orders: %v", op, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		h.logger.Errorf("Handler.writeJSON: failed to marshal response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	h.writeRaw(w, status, data)
+}
 
-	h.logger.Errorf("Handler.handleGetOrderError: failed to get order %s: %v", orderUID, err)
-	http.Error(w, "Internal server error", http.StatusInternalServerError)
+// writeRaw отвечает уже сериализованным JSON
+func (h *Handler) writeRaw(w http.ResponseWriter, status int, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(data); err != nil {
+		h.logger.Errorf("Handler.writeRaw: failed to write response: %v", err)
+	}
+}
+
+func setCORSHeaders(w http.ResponseWriter) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+}
+
+func parseOrderFilter(query url.Values) (models.OrderFilter, error) {
+	filter := models.OrderFilter{
+		CustomerID:      query.Get("customer_id"),
+		DeliveryService: query.Get("delivery_service"),
+		Locale:          query.Get("locale"),
+		Currency:        query.Get("currency"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return filter, fmt.Errorf("invalid limit %q", limit)
+		}
+		filter.Limit = value
+	}
+	if dateFrom := query.Get("date_from"); dateFrom != "" {
+		value, err := time.Parse(time.RFC3339, dateFrom)
+		if err != nil {
+			return filter, fmt.Errorf("invalid date_from %q: expected RFC3339", dateFrom)
+		}
+		filter.DateFrom = value
+	}
+	if dateTo := query.Get("date_to"); dateTo != "" {
+		value, err := time.Parse(time.RFC3339, dateTo)
+		if err != nil {
+			return filter, fmt.Errorf("invalid date_to %q: expected RFC3339", dateTo)
+		}
+		filter.DateTo = value
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		value, err := DecodeCursor(cursor)
+		if err != nil {
+			return filter, err
+		}
+		filter.Cursor = value
+	}
+	return filter, nil
+}
+
+func parseItemID(query url.Values, name string) (int64, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return value, nil
 }
 
 func getParamFromPath(path string) string {
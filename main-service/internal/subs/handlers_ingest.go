@@ -0,0 +1,263 @@
+package subs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"orders/pkg/models"
+	"strings"
+)
+
+// HeaderIdempotencyKey — заголовок с ключом идемпотентности запроса на создание заказов
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+const (
+	maxOrderBodyBytes    = 1 << 20
+	maxBatchBodyBytes    = 16 << 20
+	maxBatchOrders       = 1000
+	maxIdempotencyKeyLen = 255
+)
+
+// errorResponse — тело ответа с ошибкой; Fields заполняется для ошибок валидации
+type errorResponse struct {
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// createResult — результат создания одного заказа
+type createResult struct {
+	OrderUID string             `json:"order_uid"`
+	Status   models.OrderStatus `json:"status,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	Fields   []FieldError       `json:"fields,omitempty"`
+}
+
+// batchResponse — результат создания пачки заказов, по одному элементу на заказ в порядке запроса
+type batchResponse struct {
+	Created int            `json:"created"`
+	Failed  int            `json:"failed"`
+	Results []createResult `json:"results"`
+}
+
+// CreateOrderFromHTTP обрабатывает POST /order: создаёт заказ из тела запроса.
+// Повтор запроса с тем же Idempotency-Key возвращает сохранённый ответ.
+func (h *Handler) CreateOrderFromHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r, maxOrderBodyBytes)
+	if !ok {
+		return
+	}
+	h.withIdempotency(w, r, body, func(ctx context.Context) (int, any) {
+		var order models.OrderJSON
+		if err := json.Unmarshal(body, &order); err != nil {
+			return http.StatusBadRequest, decodeErrorResponse(err)
+		}
+		if err := ValidateOrder(&order); err != nil {
+			return http.StatusBadRequest, toErrorResponse(err)
+		}
+
+		if err := h.service.Create(ctx, &order); err != nil {
+			status := createErrorStatus(err)
+			if status >= http.StatusInternalServerError {
+				h.logger.Errorf("Handler.CreateOrderFromHTTP: order %s: %v", order.OrderUID, err)
+			}
+			return status, toErrorResponse(err)
+		}
+		return http.StatusCreated, createResult{OrderUID: order.OrderUID, Status: order.Status}
+	})
+}
+
+// CreateOrdersBatchFromHTTP обрабатывает POST /orders:batch: создаёт заказы из JSON-массива.
+// Невалидные заказы не мешают сохранению остальных; результат возвращается для каждого заказа.
+func (h *Handler) CreateOrdersBatchFromHTTP(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readBody(w, r, maxBatchBodyBytes)
+	if !ok {
+		return
+	}
+	h.withIdempotency(w, r, body, func(ctx context.Context) (int, any) {
+		var orders []models.OrderJSON
+		if err := json.Unmarshal(body, &orders); err != nil {
+			return http.StatusBadRequest, decodeErrorResponse(err)
+		}
+		if len(orders) == 0 || len(orders) > maxBatchOrders {
+			return http.StatusBadRequest, errorResponse{
+				Error: fmt.Sprintf("batch must contain from 1 to %d orders", maxBatchOrders),
+			}
+		}
+
+		response := batchResponse{Results: make([]createResult, len(orders))}
+		valid := make([]*models.OrderJSON, 0, len(orders))
+		positions := make([]int, 0, len(orders))
+		for i := range orders {
+			response.Results[i].OrderUID = orders[i].OrderUID
+			if err := ValidateOrder(&orders[i]); err != nil {
+				response.Results[i].setError(err)
+				continue
+			}
+			valid = append(valid, &orders[i])
+			positions = append(positions, i)
+		}
+
+		if len(valid) > 0 {
+			results, err := h.service.CreateBatch(ctx, valid)
+			if err != nil {
+				status := createErrorStatus(err)
+				h.logger.Errorf("Handler.CreateOrdersBatchFromHTTP: %v", err)
+				return status, toErrorResponse(err)
+			}
+			for j, err := range results {
+				result := &response.Results[positions[j]]
+				if err != nil {
+					result.setError(err)
+					continue
+				}
+				result.Status = valid[j].Status
+			}
+		}
+
+		for _, result := range response.Results {
+			if result.Error != "" {
+				response.Failed++
+			} else {
+				response.Created++
+			}
+		}
+		return http.StatusOK, response
+	})
+}
+
+// readBody читает тело POST запроса не длиннее limit байт
+func (h *Handler) readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: "method not allowed"})
+		return nil, false
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			h.writeJSON(w, http.StatusRequestEntityTooLarge,
+				errorResponse{Error: fmt.Sprintf("request body exceeds %d bytes", limit)})
+			return nil, false
+		}
+		h.writeJSON(w, http.StatusBadRequest, errorResponse{Error: "failed to read request body"})
+		return nil, false
+	}
+	return body, true
+}
+
+// withIdempotency выполняет process и отвечает его результатом. Если задан Idempotency-Key,
+// ответ сохраняется, и повтор запроса с тем же ключом и телом получает его без повторной обработки.
+// Ответы 5xx не сохраняются: такой запрос можно повторить с тем же ключом.
+func (h *Handler) withIdempotency(w http.ResponseWriter, r *http.Request, body []byte,
+	process func(ctx context.Context) (int, any)) {
+	ctx := r.Context()
+	key := r.Header.Get(HeaderIdempotencyKey)
+	if key == "" || h.idempotency == nil {
+		status, response := process(ctx)
+		h.writeJSON(w, status, response)
+		return
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		h.writeJSON(w, http.StatusBadRequest,
+			errorResponse{Error: fmt.Sprintf("%s must not exceed %d characters", HeaderIdempotencyKey, maxIdempotencyKeyLen)})
+		return
+	}
+
+	stored, err := h.idempotency.Reserve(ctx, key, requestHash(r, body))
+	switch {
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		h.writeJSON(w, http.StatusUnprocessableEntity, errorResponse{Error: err.Error()})
+		return
+	case errors.Is(err, ErrIdempotencyInProgress):
+		h.writeJSON(w, http.StatusConflict, errorResponse{Error: err.Error()})
+		return
+	case err != nil:
+		h.logger.Errorf("Handler.withIdempotency: key %s: %v", key, err)
+		h.writeJSON(w, createErrorStatus(err), errorResponse{Error: "failed to check idempotency key"})
+		return
+	case stored != nil:
+		w.Header().Set("Idempotent-Replayed", "true")
+		h.writeRaw(w, stored.StatusCode, stored.Body)
+		return
+	}
+
+	status, response := process(ctx)
+	data, err := json.Marshal(response)
+	if err != nil {
+		h.logger.Errorf("Handler.withIdempotency: failed to marshal response: %v", err)
+		status, data = http.StatusInternalServerError, []byte(`{"error":"internal server error"}`)
+	}
+
+	// Ключ фиксируется, даже если клиент уже отключился
+	ctx = context.WithoutCancel(ctx)
+	if status >= http.StatusInternalServerError {
+		err = h.idempotency.Release(ctx, key)
+	} else {
+		err = h.idempotency.Complete(ctx, key, StoredResponse{StatusCode: status, Body: data})
+	}
+	if err != nil {
+		h.logger.Errorf("Handler.withIdempotency: key %s: %v", key, err)
+	}
+	h.writeRaw(w, status, data)
+}
+
+// requestHash связывает ключ идемпотентности с конкретным запросом: методом, путём и телом
+func requestHash(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+func (res *createResult) setError(err error) {
+	response := toErrorResponse(err)
+	res.Error = response.Error
+	res.Fields = response.Fields
+}
+
+// createErrorStatus возвращает HTTP статус для ошибки создания заказа
+func createErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrDuplicate):
+		return http.StatusConflict
+	case errors.Is(err, ErrTransient):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// toErrorResponse формирует тело ответа с ошибкой; для ValidationError перечисляются поля
+func toErrorResponse(err error) errorResponse {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return errorResponse{Error: ErrValidation.Error(), Fields: validationErr.Fields}
+	}
+	if createErrorStatus(err) == http.StatusInternalServerError {
+		return errorResponse{Error: "internal server error"}
+	}
+	return errorResponse{Error: err.Error()}
+}
+
+// decodeErrorResponse описывает ошибку разбора JSON; для неверного типа значения указывается поле
+func decodeErrorResponse(err error) errorResponse {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return errorResponse{
+			Error: ErrValidation.Error(),
+			Fields: []FieldError{{
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value),
+			}},
+		}
+	}
+	return errorResponse{Error: "invalid JSON: " + strings.TrimPrefix(err.Error(), "json: ")}
+}
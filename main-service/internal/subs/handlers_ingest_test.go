@@ -0,0 +1,234 @@
+package subs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"orders/mocks"
+	"orders/pkg/models"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memIdempotencyStore — IdempotencyStore в памяти для тестов
+type memIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]memIdempotencyEntry
+}
+
+type memIdempotencyEntry struct {
+	hash     string
+	response *StoredResponse
+}
+
+func newMemIdempotencyStore() *memIdempotencyStore {
+	return &memIdempotencyStore{keys: make(map[string]memIdempotencyEntry)}
+}
+
+func (s *memIdempotencyStore) Reserve(_ context.Context, key, requestHash string) (*StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, found := s.keys[key]
+	switch {
+	case !found:
+		s.keys[key] = memIdempotencyEntry{hash: requestHash}
+		return nil, nil
+	case entry.hash != requestHash:
+		return nil, ErrIdempotencyKeyReused
+	case entry.response == nil:
+		return nil, ErrIdempotencyInProgress
+	}
+	return entry.response, nil
+}
+
+func (s *memIdempotencyStore) Complete(_ context.Context, key string, response StoredResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.keys[key]
+	entry.response = &response
+	s.keys[key] = entry
+	return nil
+}
+
+func (s *memIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, key)
+	return nil
+}
+
+func validTestOrder(orderUID string) models.OrderJSON {
+	return models.OrderJSON{
+		OrderUID:    orderUID,
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Locale:      "en",
+		DateCreated: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Delivery: models.Delivery{
+			OrderUID: orderUID, Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction: orderUID, Currency: "USD", Provider: "wbpay",
+			Amount: 1817, PaymentDT: 1637907727, Bank: "alpha",
+		},
+		Items: []models.Item{{
+			ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, RID: "ab4219087a764ae0btest",
+			Name: "Mascaras", TotalPrice: 317, NmID: 2389212,
+		}},
+	}
+}
+
+func newIngestTestHandler(store IdempotencyStore) (*Handler, *mocks.OrderRepository) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+	mockCache.On("Delete", mock.Anything).Maybe()
+	mockCache.On("DeleteIndex", mock.Anything).Maybe()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	return NewHandler(service, getTestLogger(), store), mockRepo
+}
+
+func postJSON(handler http.HandlerFunc, path string, body any, key string) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestHandler_CreateOrderFromHTTP(t *testing.T) {
+	handler, mockRepo := newIngestTestHandler(nil)
+	order := validTestOrder("b563feb7b2b84b6test")
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.OrderJSON")).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(*models.OrderJSON).Status = models.StatusCreated
+		}).Return(true, nil).Once()
+
+	rec := postJSON(handler.CreateOrderFromHTTP, "/order", order, "")
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var result createResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, order.OrderUID, result.OrderUID)
+	assert.Equal(t, models.StatusCreated, result.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandler_CreateOrderFromHTTP_ValidationErrors(t *testing.T) {
+	handler, mockRepo := newIngestTestHandler(nil)
+	order := validTestOrder("b563feb7b2b84b6test")
+	order.Locale = "english"
+	order.Payment.Currency = "XXXX"
+
+	rec := postJSON(handler.CreateOrderFromHTTP, "/order", order, "")
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var response errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	fields := make([]string, 0, len(response.Fields))
+	for _, f := range response.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.ElementsMatch(t, []string{"OrderJSON.Locale", "OrderJSON.Payment.Currency"}, fields)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestHandler_CreateOrderFromHTTP_TypeError(t *testing.T) {
+	handler, _ := newIngestTestHandler(nil)
+
+	rec := postJSON(handler.CreateOrderFromHTTP, "/order", map[string]any{"sm_id": "ninety-nine"}, "")
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var response errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Fields, 1)
+	assert.Equal(t, "sm_id", response.Fields[0].Field)
+}
+
+func TestHandler_CreateOrderFromHTTP_Duplicate(t *testing.T) {
+	handler, mockRepo := newIngestTestHandler(nil)
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(false, ErrDuplicate).Once()
+
+	rec := postJSON(handler.CreateOrderFromHTTP, "/order", validTestOrder("b563feb7b2b84b6test"), "")
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestHandler_CreateOrderFromHTTP_IdempotentReplay(t *testing.T) {
+	handler, mockRepo := newIngestTestHandler(newMemIdempotencyStore())
+	order := validTestOrder("b563feb7b2b84b6test")
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(true, nil).Once()
+
+	first := postJSON(handler.CreateOrderFromHTTP, "/order", order, "key-1")
+	second := postJSON(handler.CreateOrderFromHTTP, "/order", order, "key-1")
+
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+	mockRepo.AssertNumberOfCalls(t, "Create", 1)
+
+	order.OrderUID = "another-order-uid"
+	reused := postJSON(handler.CreateOrderFromHTTP, "/order", order, "key-1")
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+}
+
+func TestHandler_CreateOrderFromHTTP_ReleasesKeyOnFailure(t *testing.T) {
+	handler, mockRepo := newIngestTestHandler(newMemIdempotencyStore())
+	order := validTestOrder("b563feb7b2b84b6test")
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(false, ErrTransient).Once()
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(true, nil).Once()
+
+	failed := postJSON(handler.CreateOrderFromHTTP, "/order", order, "key-1")
+	retried := postJSON(handler.CreateOrderFromHTTP, "/order", order, "key-1")
+
+	assert.Equal(t, http.StatusServiceUnavailable, failed.Code)
+	assert.Equal(t, http.StatusCreated, retried.Code)
+	mockRepo.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func TestHandler_CreateOrdersBatchFromHTTP(t *testing.T) {
+	handler, mockRepo := newIngestTestHandler(nil)
+	invalid := validTestOrder("short")
+	orders := []models.OrderJSON{
+		validTestOrder("order-uid-0001"),
+		invalid,
+		validTestOrder("order-uid-0002"),
+	}
+	mockRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(batch []*models.OrderJSON) bool {
+		return len(batch) == 2 && batch[0].OrderUID == "order-uid-0001" && batch[1].OrderUID == "order-uid-0002"
+	})).Return([]bool{true, false}, []error{nil, ErrDuplicate}, nil).Once()
+
+	rec := postJSON(handler.CreateOrdersBatchFromHTTP, "/orders:batch", orders, "")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response batchResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Created)
+	assert.Equal(t, 2, response.Failed)
+	require.Len(t, response.Results, 3)
+	assert.Empty(t, response.Results[0].Error)
+	assert.Equal(t, ErrValidation.Error(), response.Results[1].Error)
+	assert.Equal(t, "OrderJSON.OrderUID", response.Results[1].Fields[0].Field)
+	assert.Equal(t, ErrDuplicate.Error(), response.Results[2].Error)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandler_CreateOrdersBatchFromHTTP_Empty(t *testing.T) {
+	handler, _ := newIngestTestHandler(nil)
+
+	rec := postJSON(handler.CreateOrdersBatchFromHTTP, "/orders:batch", []models.OrderJSON{}, "")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
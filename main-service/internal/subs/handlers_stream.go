@@ -0,0 +1,173 @@
+package subs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"orders/pkg/models"
+	"time"
+)
+
+const (
+	// orderStreamBuffer — сколько заказов может ждать отправки одному подписчику
+	orderStreamBuffer = 256
+	// orderStreamHeartbeat — период комментариев, которые не дают прокси закрыть простаивающий поток
+	orderStreamHeartbeat = 15 * time.Second
+	// orderStreamWriteTimeout — сколько ждать записи события, прежде чем отключить медленного клиента
+	orderStreamWriteTimeout = 10 * time.Second
+)
+
+// Представления заказа в потоке
+const (
+	streamViewSummary = "summary"
+	streamViewFull    = "full"
+)
+
+// orderSummary — краткое представление заказа для потока новых заказов
+type orderSummary struct {
+	OrderUID        string             `json:"order_uid"`
+	TrackNumber     string             `json:"track_number"`
+	CustomerID      string             `json:"customer_id"`
+	DeliveryService string             `json:"delivery_service"`
+	Status          models.OrderStatus `json:"status"`
+	Amount          int                `json:"amount"`
+	Currency        string             `json:"currency"`
+	Items           int                `json:"items"`
+	DateCreated     time.Time          `json:"date_created"`
+}
+
+func toOrderSummary(order *models.OrderJSON) orderSummary {
+	return orderSummary{
+		OrderUID:        order.OrderUID,
+		TrackNumber:     order.TrackNumber,
+		CustomerID:      order.CustomerID,
+		DeliveryService: order.DeliveryService,
+		Status:          order.Status,
+		Amount:          order.Payment.Amount,
+		Currency:        order.Payment.Currency,
+		Items:           len(order.Items),
+		DateCreated:     order.DateCreated,
+	}
+}
+
+// StreamOrdersFromHTTP обрабатывает GET /orders/stream: отправляет сохранённые заказы
+// как Server-Sent Events, пока клиент не отключится или сервер не начнёт остановку.
+// Параметры: delivery_service, customer_id и view (summary по умолчанию или full).
+func (h *Handler) StreamOrdersFromHTTP(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		h.logger.Warnf("Handler.StreamOrdersFromHTTP: invalid method %s", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	view := query.Get("view")
+	switch view {
+	case "":
+		view = streamViewSummary
+	case streamViewSummary, streamViewFull:
+	default:
+		http.Error(w, fmt.Sprintf("invalid view %q: expected summary or full", view), http.StatusBadRequest)
+		return
+	}
+	filter := SubscriptionFilter{
+		DeliveryService: query.Get("delivery_service"),
+		CustomerID:      query.Get("customer_id"),
+	}
+
+	sub := h.service.SubscribeNewOrders(r.Context(), filter, orderStreamBuffer)
+	stream := &eventStream{w: w, rc: http.NewResponseController(w)}
+	defer func() { _ = stream.rc.SetWriteDeadline(time.Time{}) }()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := stream.write("retry: 3000\n\n"); err != nil {
+		h.logger.Warnf("Handler.StreamOrdersFromHTTP: streaming is not supported: %v", err)
+		return
+	}
+	h.logger.Infof("Handler.StreamOrdersFromHTTP: subscriber connected (delivery_service=%q, customer_id=%q)",
+		filter.DeliveryService, filter.CustomerID)
+
+	heartbeat := time.NewTicker(orderStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case order, ok := <-sub.Orders():
+			if !ok {
+				return
+			}
+			if err = stream.dropped(sub.TakeDropped()); err != nil {
+				break
+			}
+			var data any = order
+			if view == streamViewSummary {
+				data = toOrderSummary(order)
+			}
+			err = stream.event("order", order.OrderUID, data)
+		case <-heartbeat.C:
+			if err = stream.dropped(sub.TakeDropped()); err == nil {
+				err = stream.write(": heartbeat\n\n")
+			}
+		case <-h.streamsDone:
+			return
+		case <-r.Context().Done():
+			return
+		}
+		if err != nil {
+			h.logger.Warnf("Handler.StreamOrdersFromHTTP: subscriber disconnected: %v", err)
+			return
+		}
+	}
+}
+
+// CloseStreams завершает потоки новых заказов, чтобы остановка HTTP сервера их не ждала
+func (h *Handler) CloseStreams() {
+	h.closeStreams.Do(func() { close(h.streamsDone) })
+}
+
+// eventStream пишет события Server-Sent Events и сразу отправляет их клиенту
+type eventStream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// event отправляет событие с JSON-данными
+func (s *eventStream) event(name, id string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	return s.write(fmt.Sprintf("event: %s\nid: %s\ndata: %s\n\n", name, id, payload))
+}
+
+// dropped сообщает клиенту, сколько заказов он пропустил из-за заполненного буфера
+func (s *eventStream) dropped(count int64) error {
+	if count == 0 {
+		return nil
+	}
+	return s.write(fmt.Sprintf("event: dropped\ndata: {\"count\":%d}\n\n", count))
+}
+
+// write пишет текст с ограничением по времени: клиент, который не читает поток,
+// отключается, а не задерживает остальные события
+func (s *eventStream) write(text string) error {
+	err := s.rc.SetWriteDeadline(time.Now().Add(orderStreamWriteTimeout))
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := s.w.Write([]byte(text)); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -0,0 +1,167 @@
+package subs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"orders/mocks"
+	"orders/pkg/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent — событие Server-Sent Events, прочитанное тестовым клиентом
+type sseEvent struct {
+	name string
+	id   string
+	data string
+}
+
+// openOrderStream подключается к /orders/stream и ждёт начала потока, после которого подписка уже действует
+func openOrderStream(t *testing.T, url string) (*bufio.Reader, func()) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(line, "retry:"))
+	return reader, func() {
+		cancel()
+		_ = resp.Body.Close()
+	}
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event.name != "":
+			return event
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func newStreamTestServer(t *testing.T) (*Handler, *Service) {
+	t.Helper()
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(true, nil)
+	mockCache.On("Delete", mock.Anything)
+	mockCache.On("DeleteIndex", mock.Anything)
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	return NewHandler(service, getTestLogger(), nil), service
+}
+
+func TestHandler_StreamOrdersFromHTTP_Filter(t *testing.T) {
+	handler, service := newStreamTestServer(t)
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamOrdersFromHTTP))
+	defer server.Close()
+
+	reader, closeStream := openOrderStream(t, server.URL+"/orders/stream?delivery_service=meest")
+	defer closeStream()
+
+	other := validTestOrder("order-uid-0001")
+	other.DeliveryService = "cdek"
+	wanted := validTestOrder("order-uid-0002")
+	wanted.DeliveryService = "meest"
+	require.NoError(t, service.Create(context.Background(), &other))
+	require.NoError(t, service.Create(context.Background(), &wanted))
+
+	event := readEvent(t, reader)
+	assert.Equal(t, "order", event.name)
+	assert.Equal(t, "order-uid-0002", event.id)
+
+	var summary orderSummary
+	require.NoError(t, json.Unmarshal([]byte(event.data), &summary))
+	assert.Equal(t, "meest", summary.DeliveryService)
+	assert.Equal(t, wanted.Payment.Amount, summary.Amount)
+	assert.Equal(t, 1, summary.Items)
+}
+
+func TestHandler_StreamOrdersFromHTTP_FullView(t *testing.T) {
+	handler, service := newStreamTestServer(t)
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamOrdersFromHTTP))
+	defer server.Close()
+
+	reader, closeStream := openOrderStream(t, server.URL+"/orders/stream?view=full")
+	defer closeStream()
+
+	order := validTestOrder("order-uid-0001")
+	require.NoError(t, service.Create(context.Background(), &order))
+
+	var streamed models.OrderJSON
+	require.NoError(t, json.Unmarshal([]byte(readEvent(t, reader).data), &streamed))
+	assert.Equal(t, order.Delivery, streamed.Delivery)
+	assert.Equal(t, order.Items, streamed.Items)
+}
+
+func TestHandler_StreamOrdersFromHTTP_InvalidView(t *testing.T) {
+	handler, _ := newStreamTestServer(t)
+	rec := httptest.NewRecorder()
+
+	handler.StreamOrdersFromHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/stream?view=compact", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandler_StreamOrdersFromHTTP_CloseStreams(t *testing.T) {
+	handler, _ := newStreamTestServer(t)
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamOrdersFromHTTP))
+	defer server.Close()
+
+	reader, closeStream := openOrderStream(t, server.URL+"/orders/stream")
+	defer closeStream()
+
+	handler.CloseStreams()
+
+	// После пустой строки, завершающей retry, сервер закрывает ответ
+	rest, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "\n", string(rest))
+}
+
+func TestOrderBroker_DropsForSlowSubscriber(t *testing.T) {
+	broker := newOrderBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	sub := broker.subscribe(ctx, SubscriptionFilter{CustomerID: "test"}, 1)
+
+	first, second, third := testOrder("order-1"), testOrder("order-2"), testOrder("order-3")
+	first.CustomerID, second.CustomerID, third.CustomerID = "test", "test", "other"
+	broker.publish(&first)
+	broker.publish(&second)
+	broker.publish(&third)
+
+	assert.Equal(t, "order-1", (<-sub.Orders()).OrderUID)
+	assert.Equal(t, int64(1), sub.TakeDropped())
+	assert.Equal(t, int64(0), sub.TakeDropped())
+
+	cancel()
+	_, ok := <-sub.Orders()
+	assert.False(t, ok)
+}
@@ -0,0 +1,29 @@
+package subs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"orders/mocks"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestHandler_ListOrdersFromHTTP_ItemWithoutIDs тестирует, что поиск по товару
+// без chrt_id и nm_id отклоняется до обращения к БД
+func TestHandler_ListOrdersFromHTTP_ItemWithoutIDs(t *testing.T) {
+	for _, query := range []string{"chrt_id=", "nm_id=", "chrt_id=&nm_id=", "nm_id=0"} {
+		t.Run(query, func(t *testing.T) {
+			mockRepo := &mocks.OrderRepository{}
+			service := NewService(mockRepo, getTestLogger(), &mocks.Cache{}, CacheInvalidate)
+			handler := NewHandler(service, getTestLogger(), nil)
+
+			rec := httptest.NewRecorder()
+			handler.ListOrdersFromHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders?"+query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			mockRepo.AssertNotCalled(t, "GetOrderUIDsByItem", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
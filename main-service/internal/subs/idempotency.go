@@ -0,0 +1,122 @@
+package subs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Ошибки ключа идемпотентности
+var (
+	// ErrIdempotencyKeyReused — ключ уже использован для запроса с другим телом
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+	// ErrIdempotencyInProgress — запрос с этим ключом ещё обрабатывается
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+// idempotencyLockTimeout — через сколько незавершённая обработка ключа считается брошенной
+// (например, реплика упала, не сохранив ответ), и ключ можно занять заново
+const idempotencyLockTimeout = time.Minute
+
+// StoredResponse — ответ, сохранённый для повторов запроса с тем же ключом
+type StoredResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// IdempotencyStore хранит ответы на запросы с ключом идемпотентности
+type IdempotencyStore interface {
+	// Reserve занимает ключ для обработки запроса. Если запрос с этим ключом уже выполнен,
+	// возвращается сохранённый ответ; если он выполняется — ErrIdempotencyInProgress.
+	Reserve(ctx context.Context, key, requestHash string) (*StoredResponse, error)
+	// Complete сохраняет ответ на запрос, занявший ключ
+	Complete(ctx context.Context, key string, response StoredResponse) error
+	// Release освобождает ключ, чтобы запрос можно было повторить
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyRepository реализует IdempotencyStore в PostgreSQL
+type IdempotencyRepository struct {
+	client *pgxpool.Pool
+	ttl    time.Duration
+}
+
+// NewIdempotencyRepository создает новый экземпляр IdempotencyRepository.
+// Ответы хранятся ttl, после чего ключ можно использовать заново.
+func NewIdempotencyRepository(client *pgxpool.Pool, ttl time.Duration) *IdempotencyRepository {
+	return &IdempotencyRepository{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Reserve занимает ключ или возвращает сохранённый для него ответ
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key, requestHash string) (*StoredResponse, error) {
+	now := time.Now()
+	_, err := r.client.Exec(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE created_at < $2 OR (key = $1 AND status_code IS NULL AND created_at < $3)`,
+		key, now.Add(-r.ttl), now.Add(-idempotencyLockTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("IdempotencyRepository.Reserve: delete expired: %w", classifyError(err))
+	}
+
+	tag, err := r.client.Exec(ctx, `
+		INSERT INTO idempotency_keys (key, request_hash) VALUES ($1, $2)
+		ON CONFLICT (key) DO NOTHING`,
+		key, requestHash)
+	if err != nil {
+		return nil, fmt.Errorf("IdempotencyRepository.Reserve: insert: %w", classifyError(err))
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	var (
+		storedHash string
+		statusCode *int
+		body       []byte
+	)
+	err = r.client.QueryRow(ctx,
+		`SELECT request_hash, status_code, response_body FROM idempotency_keys WHERE key = $1`,
+		key).Scan(&storedHash, &statusCode, &body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Ключ удалён между вставкой и чтением — запрос можно повторить
+		return nil, fmt.Errorf("%w: key %s was released concurrently", ErrIdempotencyInProgress, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("IdempotencyRepository.Reserve: select: %w", classifyError(err))
+	}
+
+	switch {
+	case storedHash != requestHash:
+		return nil, ErrIdempotencyKeyReused
+	case statusCode == nil:
+		return nil, ErrIdempotencyInProgress
+	}
+	return &StoredResponse{StatusCode: *statusCode, Body: body}, nil
+}
+
+// Complete сохраняет ответ для ключа
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, response StoredResponse) error {
+	_, err := r.client.Exec(ctx,
+		`UPDATE idempotency_keys SET status_code = $2, response_body = $3 WHERE key = $1`,
+		key, response.StatusCode, response.Body)
+	if err != nil {
+		return fmt.Errorf("IdempotencyRepository.Complete: %w", classifyError(err))
+	}
+	return nil
+}
+
+// Release удаляет незавершённый ключ
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := r.client.Exec(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status_code IS NULL`, key)
+	if err != nil {
+		return fmt.Errorf("IdempotencyRepository.Release: %w", classifyError(err))
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package subs
+
+import (
+	"context"
+	"fmt"
+	"orders/pkg/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// loadBatchSize ограничивает число заказов, загружаемых одной парой запросов
+const loadBatchSize = 500
+
+// querier — общий интерфейс пула, соединения и транзакции pgx для чтения
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// selectOrdersByUIDs соединяет доставку и оплату через LEFT JOIN: заказ без них
+// должен давать ErrIncomplete, а не пропадать из результата как отсутствующий
+const selectOrdersByUIDs = `
+	SELECT o.order_uid, o.track_number, o.entry, o.locale, o.internal_signature, o.customer_id,
+		o.delivery_service, o.shardkey, o.sm_id, o.date_created, o.oof_shard, o.status,
+		d.order_uid IS NOT NULL,
+		COALESCE(d.name, ''), COALESCE(d.phone, ''), COALESCE(d.zip, ''), COALESCE(d.city, ''),
+		COALESCE(d.address, ''), COALESCE(d.region, ''), COALESCE(d.email, ''),
+		p.transaction IS NOT NULL,
+		COALESCE(p.transaction, ''), COALESCE(p.request_id, ''), COALESCE(p.currency, ''),
+		COALESCE(p.provider, ''), COALESCE(p.amount, 0), COALESCE(p.payment_dt, 0),
+		COALESCE(p.bank, ''), COALESCE(p.delivery_cost, 0), COALESCE(p.goods_total, 0),
+		COALESCE(p.custom_fee, 0)
+	FROM orders o
+	LEFT JOIN deliveries d ON d.order_uid = o.order_uid
+	LEFT JOIN payments p ON p.transaction = o.order_uid
+	WHERE o.order_uid = ANY($1)`
+
+const selectItemsByTrackNumbers = `
+	SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
+	FROM items
+	WHERE track_number = ANY($1)
+	ORDER BY id`
+
+// loadOrders загружает заказы вместе с доставкой, оплатой и товарами: один запрос с JOIN
+// для заказов и один для товаров на каждые loadBatchSize UID. Порядок результата совпадает с порядком orderUIDs,
+// отсутствующие в БД заказы пропускаются. Заказ без доставки или оплаты даёт ErrIncomplete.
+func loadOrders(ctx context.Context, q querier, orderUIDs []string) ([]models.OrderJSON, error) {
+	orders := make([]models.OrderJSON, 0, len(orderUIDs))
+	for start := 0; start < len(orderUIDs); start += loadBatchSize {
+		end := min(start+loadBatchSize, len(orderUIDs))
+		batch, err := loadOrdersBatch(ctx, q, orderUIDs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, batch...)
+	}
+	return orders, nil
+}
+
+func loadOrdersBatch(ctx context.Context, q querier, orderUIDs []string) ([]models.OrderJSON, error) {
+	rows, err := q.Query(ctx, selectOrdersByUIDs, orderUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("loadOrders: failed to get orders: %w", err)
+	}
+
+	byUID := make(map[string]*models.OrderJSON, len(orderUIDs))
+	byTrack := make(map[string]*models.OrderJSON, len(orderUIDs))
+	trackNumbers := make([]string, 0, len(orderUIDs))
+	for rows.Next() {
+		var (
+			order       models.OrderJSON
+			hasDelivery bool
+			hasPayment  bool
+		)
+		if err := rows.Scan(
+			&order.OrderUID,
+			&order.TrackNumber,
+			&order.Entry,
+			&order.Locale,
+			&order.InternalSignature,
+			&order.CustomerID,
+			&order.DeliveryService,
+			&order.ShardKey,
+			&order.SmID,
+			&order.DateCreated,
+			&order.OofShard,
+			&order.Status,
+			&hasDelivery,
+			&order.Delivery.Name,
+			&order.Delivery.Phone,
+			&order.Delivery.Zip,
+			&order.Delivery.City,
+			&order.Delivery.Address,
+			&order.Delivery.Region,
+			&order.Delivery.Email,
+			&hasPayment,
+			&order.Payment.Transaction,
+			&order.Payment.RequestID,
+			&order.Payment.Currency,
+			&order.Payment.Provider,
+			&order.Payment.Amount,
+			&order.Payment.PaymentDT,
+			&order.Payment.Bank,
+			&order.Payment.DeliveryCost,
+			&order.Payment.GoodsTotal,
+			&order.Payment.CustomFee,
+		); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("loadOrders: failed to scan order: %w", err)
+		}
+		if err := checkComplete(order.OrderUID, hasDelivery, hasPayment); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("loadOrders: %w", err)
+		}
+		order.Delivery.OrderUID = order.OrderUID
+		byUID[order.OrderUID] = &order
+		byTrack[order.TrackNumber] = &order
+		trackNumbers = append(trackNumbers, order.TrackNumber)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loadOrders: failed to read orders: %w", err)
+	}
+	if len(byUID) == 0 {
+		return nil, nil
+	}
+
+	rows, err = q.Query(ctx, selectItemsByTrackNumbers, trackNumbers)
+	if err != nil {
+		return nil, fmt.Errorf("loadOrders: failed to get items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.Item
+		if err := rows.Scan(
+			&item.ChrtID,
+			&item.TrackNumber,
+			&item.Price,
+			&item.RID,
+			&item.Name,
+			&item.Sale,
+			&item.Size,
+			&item.TotalPrice,
+			&item.NmID,
+			&item.Brand,
+			&item.Status,
+		); err != nil {
+			return nil, fmt.Errorf("loadOrders: failed to scan item: %w", err)
+		}
+		if order, ok := byTrack[item.TrackNumber]; ok {
+			order.Items = append(order.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loadOrders: failed to read items: %w", err)
+	}
+
+	orders := make([]models.OrderJSON, 0, len(byUID))
+	for _, orderUID := range orderUIDs {
+		if order, ok := byUID[orderUID]; ok {
+			orders = append(orders, *order)
+		}
+	}
+	return orders, nil
+}
+
+// checkComplete возвращает ErrIncomplete, если у заказа нет доставки или оплаты
+func checkComplete(orderUID string, hasDelivery, hasPayment bool) error {
+	var missing []string
+	if !hasDelivery {
+		missing = append(missing, "delivery")
+	}
+	if !hasPayment {
+		missing = append(missing, "payment")
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: order %s has no %s", ErrIncomplete, orderUID, strings.Join(missing, " and "))
+}
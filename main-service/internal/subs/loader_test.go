@@ -0,0 +1,21 @@
+package subs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckComplete тестирует ошибку для заказа без доставки или оплаты
+func TestCheckComplete(t *testing.T) {
+	assert.NoError(t, checkComplete("test-123", true, true))
+
+	err := checkComplete("test-123", false, true)
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Contains(t, err.Error(), "has no delivery")
+
+	err = checkComplete("test-123", false, false)
+	assert.ErrorIs(t, err, ErrIncomplete)
+	assert.Contains(t, err.Error(), "has no delivery and payment")
+}
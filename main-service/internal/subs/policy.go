@@ -0,0 +1,80 @@
+package subs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"orders/pkg/models"
+	"time"
+)
+
+// DuplicatePolicy определяет, как репозиторий обрабатывает заказ с уже существующим UID
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkipIdentical пропускает заказ с тем же содержимым и отклоняет отличающийся
+	DuplicateSkipIdentical DuplicatePolicy = "skip-if-identical"
+	// DuplicateReject отклоняет любой повторный заказ с ErrDuplicate
+	DuplicateReject DuplicatePolicy = "reject"
+	// DuplicateUpsert заменяет сохранённый заказ новым содержимым
+	DuplicateUpsert DuplicatePolicy = "upsert"
+)
+
+// ParseDuplicatePolicy разбирает политику из строки конфигурации
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(s); policy {
+	case DuplicateSkipIdentical, DuplicateReject, DuplicateUpsert:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown duplicate policy %q: expected %s, %s or %s",
+		s, DuplicateSkipIdentical, DuplicateReject, DuplicateUpsert)
+}
+
+// contentHash возвращает SHA-256 от JSON-представления заказа.
+// Поля структуры сериализуются в фиксированном порядке, поэтому
+// одинаковые заказы всегда дают одинаковый хэш. Статус не входит в хэш:
+// он меняется отдельно от содержимого и проставляется репозиторием после записи.
+func contentHash(order *models.OrderJSON) (string, error) {
+	content := *order
+	content.Status = ""
+	data, err := json.Marshal(&content)
+	if err != nil {
+		return "", fmt.Errorf("contentHash: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// storedContentHash возвращает хэш содержимого заказа в том виде, в каком его возвращает БД:
+// время создания — в UTC с точностью до микросекунд, доставка привязана к UID заказа.
+// Им сравниваются заказы, сохранённые до появления колонки content_hash.
+func storedContentHash(order *models.OrderJSON) (string, error) {
+	stored := *order
+	stored.DateCreated = order.DateCreated.UTC().Truncate(time.Microsecond)
+	stored.Delivery.OrderUID = order.OrderUID
+	return contentHash(&stored)
+}
+
+// CachePolicy определяет, как сервис обновляет кэш при записи заказа
+type CachePolicy string
+
+const (
+	// CacheInvalidate удаляет заказ из кэша после записи; следующее чтение идёт в БД
+	CacheInvalidate CachePolicy = "invalidate"
+	// CacheWriteThrough кладёт заказ в кэш после успешной записи в БД
+	CacheWriteThrough CachePolicy = "write-through"
+	// CacheWriteBehind кладёт заказ в кэш сразу и записывает его в БД в фоне.
+	// Если запись не удалась, заказ удаляется из кэша, а ошибка передаётся WriteFailureHandler.
+	CacheWriteBehind CachePolicy = "write-behind"
+)
+
+// ParseCachePolicy разбирает политику обновления кэша из строки конфигурации
+func ParseCachePolicy(s string) (CachePolicy, error) {
+	switch policy := CachePolicy(s); policy {
+	case CacheInvalidate, CacheWriteThrough, CacheWriteBehind:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown cache policy %q: expected %s, %s or %s",
+		s, CacheInvalidate, CacheWriteThrough, CacheWriteBehind)
+}
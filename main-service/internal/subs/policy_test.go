@@ -0,0 +1,86 @@
+package subs
+
+import (
+	"orders/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	for _, s := range []string{"skip-if-identical", "reject", "upsert"} {
+		policy, err := ParseDuplicatePolicy(s)
+		require.NoError(t, err)
+		assert.Equal(t, DuplicatePolicy(s), policy)
+	}
+
+	_, err := ParseDuplicatePolicy("overwrite")
+	assert.Error(t, err)
+}
+
+func TestContentHash(t *testing.T) {
+	order := &models.OrderJSON{
+		OrderUID:    "test-123",
+		TrackNumber: "WBILMTESTTRACK",
+		Items:       []models.Item{{ChrtID: 1, Price: 100}},
+	}
+	same := *order
+	same.Items = []models.Item{{ChrtID: 1, Price: 100}}
+
+	hash, err := contentHash(order)
+	require.NoError(t, err)
+	sameHash, err := contentHash(&same)
+	require.NoError(t, err)
+	assert.Equal(t, hash, sameHash)
+	assert.Len(t, hash, 64)
+
+	same.Items[0].Price = 200
+	changedHash, err := contentHash(&same)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changedHash)
+
+	withStatus := *order
+	withStatus.Status = models.StatusPaid
+	statusHash, err := contentHash(&withStatus)
+	require.NoError(t, err)
+	assert.Equal(t, hash, statusHash)
+}
+
+func TestParseCachePolicy(t *testing.T) {
+	for _, s := range []string{"invalidate", "write-through", "write-behind"} {
+		policy, err := ParseCachePolicy(s)
+		require.NoError(t, err)
+		assert.Equal(t, CachePolicy(s), policy)
+	}
+
+	_, err := ParseCachePolicy("write-around")
+	assert.Error(t, err)
+}
+
+// TestStoredContentHash тестирует, что заказ совпадает со своей копией, прочитанной из БД:
+// время создания в другой зоне и с наносекундами, доставка без UID, статус из БД
+func TestStoredContentHash(t *testing.T) {
+	order := &models.OrderJSON{
+		OrderUID:    "test-123",
+		TrackNumber: "WBILMTESTTRACK",
+		DateCreated: time.Date(2021, 11, 26, 9, 22, 19, 123456789, time.FixedZone("MSK", 3*60*60)),
+		Items:       []models.Item{{ChrtID: 1, Price: 100}},
+	}
+	stored := *order
+	stored.DateCreated = time.Date(2021, 11, 26, 6, 22, 19, 123456000, time.UTC)
+	stored.Delivery.OrderUID = "test-123"
+	stored.Status = models.StatusPaid
+
+	hash, err := storedContentHash(order)
+	require.NoError(t, err)
+	storedHash, err := storedContentHash(&stored)
+	require.NoError(t, err)
+	assert.Equal(t, hash, storedHash)
+
+	stored.Items = []models.Item{{ChrtID: 1, Price: 200}}
+	changedHash, err := storedContentHash(&stored)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changedHash)
+}
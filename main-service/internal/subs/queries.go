@@ -2,18 +2,106 @@ package subs
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"orders/pkg/models"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
-func insertOrder(ctx context.Context, tx pgx.Tx, order models.Order) error {
+// insertOrder вставляет заказ и возвращает false, если заказ с таким UID уже существует
+func insertOrder(ctx context.Context, tx pgx.Tx, order models.Order, hash string) (bool, error) {
 	query := `
 		INSERT INTO orders
-		(order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		(order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard, content_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (order_uid) DO NOTHING
 	`
-	_, err := tx.Exec(ctx, query, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard)
+	tag, err := tx.Exec(ctx, query, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard, hash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// selectOrderForUpdate блокирует существующий заказ и возвращает его хэш содержимого, трек-номер и статус.
+// Хэш пуст у заказов, сохранённых до появления колонки content_hash: он вычисляется
+// при первой повторной записи такого заказа (см. Repository.backfillContentHash).
+func selectOrderForUpdate(ctx context.Context, tx pgx.Tx, orderUID string) (string, string, models.OrderStatus, error) {
+	var (
+		hash, trackNumber string
+		status            models.OrderStatus
+	)
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE(content_hash, ''), track_number, status FROM orders WHERE order_uid = $1 FOR UPDATE`,
+		orderUID).Scan(&hash, &trackNumber, &status)
+	return hash, trackNumber, status, err
+}
+
+// setContentHash записывает хэш содержимого заказа, сохранённого до появления колонки content_hash
+func setContentHash(ctx context.Context, tx pgx.Tx, orderUID, hash string) error {
+	_, err := tx.Exec(ctx, `UPDATE orders SET content_hash = $2 WHERE order_uid = $1`, orderUID, hash)
+	return err
+}
+
+func updateOrder(ctx context.Context, tx pgx.Tx, order models.Order, hash string) error {
+	query := `
+		UPDATE orders SET
+		track_number = $2, entry = $3, locale = $4, internal_signature = $5, customer_id = $6, delivery_service = $7,
+		shardkey = $8, sm_id = $9, date_created = $10, oof_shard = $11, content_hash = $12
+		WHERE order_uid = $1
+	`
+	_, err := tx.Exec(ctx, query, order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature, order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard, hash)
+	return err
+}
+
+// newOrderEvent формирует событие о создании или замене заказа со статусом status
+func newOrderEvent(eventType string, orderJSON *models.OrderJSON, status models.OrderStatus) models.OrderEvent {
+	order := *orderJSON
+	order.Status = status
+	return models.OrderEvent{
+		Type:       eventType,
+		OrderUID:   orderJSON.OrderUID,
+		Status:     status,
+		Order:      &order,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// insertOutboxEvent записывает событие в outbox; вызывается в транзакции, изменяющей заказ,
+// поэтому событие сохраняется тогда и только тогда, когда сохраняется изменение
+func insertOutboxEvent(ctx context.Context, tx pgx.Tx, event models.OrderEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", event.Type, err)
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO outbox (aggregate_id, event_type, payload) VALUES ($1, $2, $3)`,
+		event.OrderUID, event.Type, payload)
+	return err
+}
+
+// insertStatusChange добавляет запись в историю статусов; пустой from сохраняется как NULL
+func insertStatusChange(ctx context.Context, tx pgx.Tx, orderUID string, from, to models.OrderStatus, reason string) error {
+	query := `
+		INSERT INTO order_status_history (order_uid, from_status, to_status, reason)
+		VALUES ($1, NULLIF($2, ''), $3, $4)
+	`
+	_, err := tx.Exec(ctx, query, orderUID, string(from), string(to), reason)
+	return err
+}
+
+// deleteOrderDetails удаляет доставку, оплату и товары заказа перед его перезаписью
+func deleteOrderDetails(ctx context.Context, tx pgx.Tx, orderUID, trackNumber string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM items WHERE track_number = $1`, trackNumber); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM deliveries WHERE order_uid = $1`, orderUID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `DELETE FROM payments WHERE transaction = $1`, orderUID)
 	return err
 }
 
@@ -37,12 +125,85 @@ func insertPayment(ctx context.Context, tx pgx.Tx, payment models.Payment) error
 	return err
 }
 
-func insertItems(ctx context.Context, tx pgx.Tx, item models.Item) error {
-	query := `
-		INSERT INTO items
+// insertItems вставляет все товары заказа одним многострочным INSERT
+func insertItems(ctx context.Context, tx pgx.Tx, items []models.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	const columns = 11
+	var sb strings.Builder
+	sb.WriteString(`INSERT INTO items
 		(chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-	`
-	_, err := tx.Exec(ctx, query, item.ChrtID, item.TrackNumber, item.Price, item.RID, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
+		VALUES `)
+	args := make([]any, 0, len(items)*columns)
+	for i, item := range items {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for j := 1; j <= columns; j++ {
+			if j > 1 {
+				sb.WriteString(", ")
+			}
+			fmt.Fprintf(&sb, "$%d", i*columns+j)
+		}
+		sb.WriteString(")")
+		args = append(args, item.ChrtID, item.TrackNumber, item.Price, item.RID, item.Name, item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status)
+	}
+
+	_, err := tx.Exec(ctx, sb.String(), args...)
 	return err
 }
+
+// copyOrders записывает пачку заказов со связанными сущностями через COPY
+func copyOrders(ctx context.Context, tx pgx.Tx, orders []*models.OrderJSON) error {
+	orderRows := make([][]any, 0, len(orders))
+	deliveryRows := make([][]any, 0, len(orders))
+	paymentRows := make([][]any, 0, len(orders))
+	historyRows := make([][]any, 0, len(orders))
+	outboxRows := make([][]any, 0, len(orders))
+	var itemRows [][]any
+	for _, o := range orders {
+		hash, err := contentHash(o)
+		if err != nil {
+			return err
+		}
+		orderRows = append(orderRows, []any{o.OrderUID, o.TrackNumber, o.Entry, o.Locale, o.InternalSignature, o.CustomerID, o.DeliveryService, o.ShardKey, o.SmID, o.DateCreated, o.OofShard, hash})
+		historyRows = append(historyRows, []any{o.OrderUID, string(models.StatusCreated)})
+		payload, err := json.Marshal(newOrderEvent(models.EventOrderCreated, o, models.StatusCreated))
+		if err != nil {
+			return fmt.Errorf("marshal %s event: %w", models.EventOrderCreated, err)
+		}
+		outboxRows = append(outboxRows, []any{o.OrderUID, models.EventOrderCreated, payload})
+		d := o.Delivery
+		deliveryRows = append(deliveryRows, []any{o.OrderUID, d.Name, d.Phone, d.Zip, d.City, d.Address, d.Region, d.Email})
+		p := o.Payment
+		paymentRows = append(paymentRows, []any{p.Transaction, p.RequestID, p.Currency, p.Provider, p.Amount, p.PaymentDT, p.Bank, p.DeliveryCost, p.GoodsTotal, p.CustomFee})
+		for _, i := range o.Items {
+			itemRows = append(itemRows, []any{i.ChrtID, i.TrackNumber, i.Price, i.RID, i.Name, i.Sale, i.Size, i.TotalPrice, i.NmID, i.Brand, i.Status})
+		}
+	}
+
+	copies := []struct {
+		table   string
+		columns []string
+		rows    [][]any
+	}{
+		{"orders", []string{"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id", "delivery_service", "shardkey", "sm_id", "date_created", "oof_shard", "content_hash"}, orderRows},
+		{"deliveries", []string{"order_uid", "name", "phone", "zip", "city", "address", "region", "email"}, deliveryRows},
+		{"payments", []string{"transaction", "request_id", "currency", "provider", "amount", "payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee"}, paymentRows},
+		{"items", []string{"chrt_id", "track_number", "price", "rid", "name", "sale", "size", "total_price", "nm_id", "brand", "status"}, itemRows},
+		{"order_status_history", []string{"order_uid", "to_status"}, historyRows},
+		{"outbox", []string{"aggregate_id", "event_type", "payload"}, outboxRows},
+	}
+	for _, c := range copies {
+		if len(c.rows) == 0 {
+			continue
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{c.table}, c.columns, pgx.CopyFromRows(c.rows)); err != nil {
+			return fmt.Errorf("copy %s: %w", c.table, err)
+		}
+	}
+	return nil
+}
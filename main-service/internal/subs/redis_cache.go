@@ -0,0 +1,370 @@
+package subs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"orders/internal/metrics"
+	"orders/pkg/models"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	redisOrderKeyPrefix      = "orders:order:"
+	redisIndexKeyPrefix      = "orders:index:"
+	redisMissingKeyPrefix    = "orders:missing:"
+	redisInvalidationChannel = "orders:cache:invalidate"
+	// redisIndexInvalidationChannel передаёт ключи индекса, разделённые переводом строки
+	redisIndexInvalidationChannel = "orders:cache:invalidate-index"
+
+	// redisOpTimeout ограничивает одну операцию с Redis: кэш не должен задерживать запрос к БД
+	redisOpTimeout = 500 * time.Millisecond
+	// redisWarmUpChunk — число заказов, записываемых в Redis одним pipeline при прогреве
+	redisWarmUpChunk = 500
+)
+
+// RedisCache реализует Cache поверх Redis, общего для всех реплик сервиса.
+// Перед Redis может стоять локальный LRU-кэш (near cache): его записи на других репликах
+// сбрасываются через pub/sub при инвалидации заказа.
+type RedisCache struct {
+	client     redis.UniversalClient
+	local      *LRUCache
+	ttl        time.Duration
+	staleTTL   time.Duration
+	missingTTL time.Duration
+	logger     *logrus.Logger
+
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+// redisOrder — заказ в Redis вместе со временем, до которого он считается актуальным.
+// Ключ живёт на StaleTTL дольше, чтобы устаревший заказ можно было отдать во время обновления.
+type redisOrder struct {
+	FreshUntil int64            `json:"fresh_until"`
+	Order      models.OrderJSON `json:"order"`
+}
+
+// NewRedisCache создает новый экземпляр RedisCache и подписывается на канал инвалидации.
+// Из opts используются времена жизни записей; local может быть nil — тогда все чтения идут в Redis.
+func NewRedisCache(client redis.UniversalClient, opts CacheOptions, local *LRUCache, logger *logrus.Logger) *RedisCache {
+	cache := &RedisCache{
+		client:     client,
+		local:      local,
+		ttl:        opts.TTL,
+		staleTTL:   opts.StaleTTL,
+		missingTTL: opts.MissingTTL,
+		logger:     logger,
+		done:       make(chan struct{}),
+	}
+	cache.pubsub = client.Subscribe(context.Background(), redisInvalidationChannel, redisIndexInvalidationChannel)
+	go cache.listenInvalidations()
+	return cache
+}
+
+// Get возвращает заказ из локального кэша или из Redis
+func (c *RedisCache) Get(key string) (*models.OrderJSON, bool) {
+	if c.local != nil {
+		if order, found := c.local.Get(key); found {
+			return order, true
+		}
+	}
+
+	cached, found := c.getOrder(key)
+	if !found || time.Now().UnixMilli() > cached.FreshUntil {
+		return nil, false
+	}
+	if c.local != nil {
+		c.local.Set(key, &cached.Order)
+	}
+	return &cached.Order, true
+}
+
+// GetStale возвращает заказ из локального кэша или из Redis, даже если его TTL истёк
+func (c *RedisCache) GetStale(key string) (*models.OrderJSON, bool) {
+	if c.local != nil {
+		if order, found := c.local.GetStale(key); found {
+			return order, true
+		}
+	}
+	cached, found := c.getOrder(key)
+	if !found {
+		return nil, false
+	}
+	return &cached.Order, true
+}
+
+func (c *RedisCache) getOrder(key string) (*redisOrder, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, redisOrderKeyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.observeError("get", err)
+		}
+		return nil, false
+	}
+	var cached redisOrder
+	if err := decodeCacheValue(data, &cached); err != nil {
+		c.observeError("decode", err)
+		return nil, false
+	}
+	return &cached, true
+}
+
+// Set сохраняет заказ в Redis и в локальном кэше
+func (c *RedisCache) Set(key string, value *models.OrderJSON) {
+	if c.local != nil {
+		c.local.Set(key, value)
+	}
+
+	data, err := c.encodeOrder(value)
+	if err != nil {
+		c.observeError("encode", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	_, err = c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisOrderKeyPrefix+key, data, c.ttl+c.staleTTL)
+		pipe.Del(ctx, redisMissingKeyPrefix+key)
+		return nil
+	})
+	if err != nil {
+		c.observeError("set", err)
+	}
+}
+
+// SetMissing запоминает в Redis и в локальном кэше, что заказа с таким UID нет в БД
+func (c *RedisCache) SetMissing(orderUID string) {
+	if c.missingTTL <= 0 {
+		return
+	}
+	if c.local != nil {
+		c.local.SetMissing(orderUID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+	if err := c.client.Set(ctx, redisMissingKeyPrefix+orderUID, 1, c.missingTTL).Err(); err != nil {
+		c.observeError("set_missing", err)
+	}
+}
+
+// IsMissing сообщает, отмечен ли заказ как отсутствующий в локальном кэше или в Redis
+func (c *RedisCache) IsMissing(orderUID string) bool {
+	if c.local != nil && c.local.IsMissing(orderUID) {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+	n, err := c.client.Exists(ctx, redisMissingKeyPrefix+orderUID).Result()
+	if err != nil {
+		c.observeError("is_missing", err)
+		return false
+	}
+	return n > 0
+}
+
+// Delete удаляет заказ из Redis и рассылает инвалидацию локальных кэшей всех реплик
+func (c *RedisCache) Delete(orderUID string) {
+	if c.local != nil {
+		c.local.invalidate(orderUID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, redisOrderKeyPrefix+orderUID, redisMissingKeyPrefix+orderUID)
+		pipe.Publish(ctx, redisInvalidationChannel, orderUID)
+		return nil
+	})
+	if err != nil {
+		c.observeError("delete", err)
+		return
+	}
+	metrics.CacheInvalidationsTotal.WithLabelValues("local").Inc()
+	c.logger.Infof("Cache invalidated for order: %s", orderUID)
+}
+
+// GetIndex возвращает UID заказов по ключу вторичного идентификатора
+func (c *RedisCache) GetIndex(key string) ([]string, bool) {
+	if c.local != nil {
+		if orderUIDs, found := c.local.GetIndex(key); found {
+			return orderUIDs, true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, redisIndexKeyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			c.observeError("get_index", err)
+		}
+		return nil, false
+	}
+	var orderUIDs []string
+	if err := decodeCacheValue(data, &orderUIDs); err != nil {
+		c.observeError("decode", err)
+		return nil, false
+	}
+
+	if c.local != nil {
+		c.local.SetIndex(key, orderUIDs)
+	}
+	return orderUIDs, true
+}
+
+// DeleteIndex удаляет записи индекса из Redis и из локальных кэшей всех реплик
+func (c *RedisCache) DeleteIndex(keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	if c.local != nil {
+		c.local.DeleteIndex(keys)
+	}
+
+	redisKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		redisKeys = append(redisKeys, redisIndexKeyPrefix+key)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, redisKeys...)
+		pipe.Publish(ctx, redisIndexInvalidationChannel, strings.Join(keys, "\n"))
+		return nil
+	})
+	if err != nil {
+		c.observeError("delete_index", err)
+	}
+}
+
+// SetIndex сохраняет UID заказов по ключу вторичного идентификатора
+func (c *RedisCache) SetIndex(key string, orderUIDs []string) {
+	if c.local != nil {
+		c.local.SetIndex(key, orderUIDs)
+	}
+
+	data, err := encodeCacheValue(orderUIDs)
+	if err != nil {
+		c.observeError("encode", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+	if err := c.client.Set(ctx, redisIndexKeyPrefix+key, data, c.ttl).Err(); err != nil {
+		c.observeError("set_index", err)
+	}
+}
+
+// WarmUpCache записывает заказы в Redis пачками через pipeline и прогревает локальный кэш.
+// Объём Redis ограничивается его собственной политикой maxmemory, поэтому загружаются все заказы.
+func (c *RedisCache) WarmUpCache(orders []models.OrderJSON) (int, error) {
+	for start := 0; start < len(orders); start += redisWarmUpChunk {
+		end := min(start+redisWarmUpChunk, len(orders))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*redisOpTimeout)
+		_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i := start; i < end; i++ {
+				data, err := c.encodeOrder(&orders[i])
+				if err != nil {
+					return err
+				}
+				pipe.Set(ctx, redisOrderKeyPrefix+orders[i].OrderUID, data, c.ttl+c.staleTTL)
+			}
+			return nil
+		})
+		cancel()
+		if err != nil {
+			c.observeError("warm_up", err)
+			return start, err
+		}
+	}
+
+	if c.local != nil {
+		if _, err := c.local.WarmUpCache(orders); err != nil {
+			return len(orders), err
+		}
+	}
+	c.logger.Debugf("Cache warmed up with %d orders", len(orders))
+	return len(orders), nil
+}
+
+// Close отписывается от канала инвалидации и закрывает соединение с Redis
+func (c *RedisCache) Close(ctx context.Context) error {
+	err := c.pubsub.Close()
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+	}
+	return errors.Join(err, c.client.Close())
+}
+
+// Ping проверяет соединение с Redis
+func (c *RedisCache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+// Name возвращает имя ресурса для closer.Manager
+func (c *RedisCache) Name() string { return "redis cache" }
+
+// listenInvalidations удаляет из локального кэша заказы и записи индекса,
+// инвалидированные другими репликами
+func (c *RedisCache) listenInvalidations() {
+	defer close(c.done)
+	for msg := range c.pubsub.Channel() {
+		if c.local == nil {
+			continue
+		}
+		if msg.Channel == redisIndexInvalidationChannel {
+			c.local.DeleteIndex(strings.Split(msg.Payload, "\n"))
+		} else {
+			c.local.invalidate(msg.Payload)
+		}
+		metrics.CacheInvalidationsTotal.WithLabelValues("pubsub").Inc()
+	}
+}
+
+func (c *RedisCache) observeError(operation string, err error) {
+	metrics.CacheErrorsTotal.WithLabelValues(operation).Inc()
+	c.logger.Warnf("RedisCache.%s: %v", operation, err)
+}
+
+func (c *RedisCache) encodeOrder(order *models.OrderJSON) ([]byte, error) {
+	return encodeCacheValue(&redisOrder{
+		FreshUntil: time.Now().Add(c.ttl).UnixMilli(),
+		Order:      *order,
+	})
+}
+
+// encodeCacheValue сериализует значение в MessagePack: он компактнее JSON и быстрее разбирается.
+// Имена полей берутся из тегов json, чтобы не дублировать теги в моделях.
+func encodeCacheValue(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeCacheValue(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
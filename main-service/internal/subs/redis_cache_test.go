@@ -0,0 +1,174 @@
+package subs
+
+import (
+	"context"
+	"orders/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCacheOptions = CacheOptions{MaxEntries: 100, TTL: time.Minute, MissingTTL: time.Minute}
+
+func newTestRedisCache(t *testing.T, mr *miniredis.Miniredis, opts CacheOptions, local *LRUCache) *RedisCache {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	cache := NewRedisCache(client, opts, local, getTestLogger())
+	t.Cleanup(func() { _ = cache.Close(context.Background()) })
+	return cache
+}
+
+func TestRedisCache_SetGet(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := newTestRedisCache(t, mr, testCacheOptions, nil)
+
+	order := testOrder("order-1")
+	order.DateCreated = time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC)
+	order.Payment.Amount = 1817
+	cache.Set(order.OrderUID, &order)
+
+	got, found := cache.Get(order.OrderUID)
+	require.True(t, found)
+	assert.Equal(t, order.OrderUID, got.OrderUID)
+	assert.Equal(t, order.Items, got.Items)
+	assert.Equal(t, order.Payment.Amount, got.Payment.Amount)
+	assert.True(t, order.DateCreated.Equal(got.DateCreated))
+
+	ttl := mr.TTL(redisOrderKeyPrefix + order.OrderUID)
+	assert.Equal(t, time.Minute, ttl)
+
+	mr.FastForward(2 * time.Minute)
+	_, found = cache.Get(order.OrderUID)
+	assert.False(t, found)
+}
+
+func TestRedisCache_Index(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := newTestRedisCache(t, mr, testCacheOptions, nil)
+
+	cache.SetIndex(trackNumberKey("TRACK1"), []string{"order-1", "order-2"})
+
+	uids, found := cache.GetIndex(trackNumberKey("TRACK1"))
+	require.True(t, found)
+	assert.Equal(t, []string{"order-1", "order-2"}, uids)
+
+	_, found = cache.GetIndex(trackNumberKey("TRACK2"))
+	assert.False(t, found)
+}
+
+func TestRedisCache_DeleteInvalidatesOtherReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	localA := NewLRUCache(testCacheOptions, getTestLogger())
+	replicaA := newTestRedisCache(t, mr, testCacheOptions, localA)
+	replicaB := newTestRedisCache(t, mr, testCacheOptions, NewLRUCache(testCacheOptions, getTestLogger()))
+
+	// Дожидаемся подписки обеих реплик на канал инвалидации
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub(redisInvalidationChannel)[redisInvalidationChannel] == 2
+	}, time.Second, 10*time.Millisecond)
+
+	order := testOrder("order-1")
+	replicaB.Set(order.OrderUID, &order)
+	_, found := replicaA.Get(order.OrderUID)
+	require.True(t, found)
+	_, found = localA.Get(order.OrderUID)
+	require.True(t, found)
+
+	replicaB.Delete(order.OrderUID)
+
+	assert.False(t, mr.Exists(redisOrderKeyPrefix+order.OrderUID))
+	assert.Eventually(t, func() bool {
+		_, found := localA.Get(order.OrderUID)
+		return !found
+	}, time.Second, 10*time.Millisecond)
+	_, found = replicaA.Get(order.OrderUID)
+	assert.False(t, found)
+}
+
+func TestRedisCache_DeleteIndexInvalidatesOtherReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	localA := NewLRUCache(testCacheOptions, getTestLogger())
+	replicaA := newTestRedisCache(t, mr, testCacheOptions, localA)
+	replicaB := newTestRedisCache(t, mr, testCacheOptions, NewLRUCache(testCacheOptions, getTestLogger()))
+
+	require.Eventually(t, func() bool {
+		return mr.PubSubNumSub(redisIndexInvalidationChannel)[redisIndexInvalidationChannel] == 2
+	}, time.Second, 10*time.Millisecond)
+
+	track, chrt := trackNumberKey("TRACK1"), chrtIDKey(9934930)
+	replicaA.SetIndex(track, []string{"order-1"})
+	replicaA.SetIndex(chrt, []string{"order-1"})
+
+	replicaB.DeleteIndex([]string{track, chrt})
+
+	assert.False(t, mr.Exists(redisIndexKeyPrefix+track))
+	assert.False(t, mr.Exists(redisIndexKeyPrefix+chrt))
+	assert.Eventually(t, func() bool {
+		_, trackFound := localA.GetIndex(track)
+		_, chrtFound := localA.GetIndex(chrt)
+		return !trackFound && !chrtFound
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRedisCache_Stale(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := newTestRedisCache(t, mr, CacheOptions{TTL: time.Millisecond, StaleTTL: time.Minute}, nil)
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	time.Sleep(5 * time.Millisecond)
+
+	_, found := cache.Get(order.OrderUID)
+	assert.False(t, found)
+	got, found := cache.GetStale(order.OrderUID)
+	require.True(t, found)
+	assert.Equal(t, order.OrderUID, got.OrderUID)
+}
+
+func TestRedisCache_Missing(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := newTestRedisCache(t, mr, testCacheOptions, nil)
+
+	cache.SetMissing("order-1")
+	assert.True(t, cache.IsMissing("order-1"))
+	assert.False(t, cache.IsMissing("order-2"))
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+	assert.False(t, cache.IsMissing("order-1"))
+
+	cache.SetMissing("order-3")
+	mr.FastForward(2 * time.Minute)
+	assert.False(t, cache.IsMissing("order-3"))
+}
+
+func TestRedisCache_WarmUp(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := newTestRedisCache(t, mr, testCacheOptions, nil)
+
+	orders := []models.OrderJSON{testOrder("order-1"), testOrder("order-2")}
+	loaded, err := cache.WarmUpCache(orders)
+	require.NoError(t, err)
+	assert.Equal(t, len(orders), loaded)
+
+	for _, order := range orders {
+		_, found := cache.Get(order.OrderUID)
+		assert.True(t, found)
+	}
+}
+
+func TestRedisCache_Unavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cache := newTestRedisCache(t, mr, testCacheOptions, nil)
+	mr.Close()
+
+	order := testOrder("order-1")
+	cache.Set(order.OrderUID, &order)
+
+	_, found := cache.Get(order.OrderUID)
+	assert.False(t, found)
+}
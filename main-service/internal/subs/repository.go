@@ -4,58 +4,282 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"orders/internal/metrics"
 	"orders/pkg/models"
-	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	errExist    = errors.New("record already exist")
-	errNotFound = errors.New("record not found")
-)
-
 // OrderRepository определяет интерфейс для работы с заказами в БД
 type OrderRepository interface {
-	Create(ctx context.Context, orderJSON *models.OrderJSON) error
-	GetAll(ctx context.Context) ([]models.OrderJSON, error)
+	Create(ctx context.Context, orderJSON *models.OrderJSON) (bool, error)
+	CreateBatch(ctx context.Context, orders []*models.OrderJSON) ([]bool, []error, error)
 	GetOrder(ctx context.Context, orderUID string) (*models.OrderJSON, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.OrderJSON, error)
+	GetOrderUIDByTrackNumber(ctx context.Context, trackNumber string) (string, error)
+	GetOrderUIDsByItem(ctx context.Context, chrtID, nmID int64) ([]string, error)
+	GetOrderStatus(ctx context.Context, orderUID string) (models.OrderStatus, error)
+	UpdateStatus(ctx context.Context, orderUID string, from, to models.OrderStatus, reason string) error
+	GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error)
 }
 
+// errStatusChanged — статус заказа изменился между чтением и обновлением
+var errStatusChanged = errors.New("order status changed concurrently")
+
 // Repository управляет доступом к данным в базе данных
 type Repository struct {
-	client *pgx.Conn
-	logger *logrus.Logger
-	mu     sync.Mutex
+	client          *pgxpool.Pool
+	logger          *logrus.Logger
+	duplicatePolicy DuplicatePolicy
 }
 
-// NewRepository создает новый экземпляр Repository
-func NewRepository(client *pgx.Conn, logger *logrus.Logger) *Repository {
+// NewRepository создает новый экземпляр Repository.
+// duplicatePolicy определяет поведение при записи заказа с уже существующим UID.
+func NewRepository(client *pgxpool.Pool, logger *logrus.Logger, duplicatePolicy DuplicatePolicy) *Repository {
 	return &Repository{
-		client: client,
-		logger: logger,
+		client:          client,
+		logger:          logger,
+		duplicatePolicy: duplicatePolicy,
+	}
+}
+
+// Create сохраняет заказ в базе данных.
+// Возвращает true, если заказ вставлен или заменён, и false, если сохранённый заказ
+// совпадает с новым и запись пропущена.
+// После успешной записи в orderJSON.Status записывается статус заказа в БД.
+func (r *Repository) Create(ctx context.Context, orderJSON *models.OrderJSON) (bool, error) {
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Repository.Create: begin: %w", classifyError(err))
+	}
+
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
+			r.logger.Errorf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	r.logger.Infof("Repository.Create: Transaction BEGIN for %s", orderJSON.OrderUID)
+	status, written, err := r.writeOrder(ctx, tx, orderJSON)
+	if err != nil {
+		return false, err
+	}
+	r.logger.Info("Repository.Create: Transaction COMMIT")
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("Repository.Create: commit: %w", classifyError(err))
 	}
+	orderJSON.Status = status
+	return written, nil
 }
 
-// Create сохраняет заказ в базе данных
-func (r *Repository) Create(ctx context.Context, orderJSON *models.OrderJSON) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// CreateBatch сохраняет пачку заказов в одной транзакции и возвращает ошибку для каждого заказа.
+// Сначала все заказы записываются через COPY; если это не удалось, заказы записываются по одному
+// под точками сохранения, чтобы ошибка одного заказа не отменяла остальные.
+// Общая ошибка возвращается, если не удалось начать или зафиксировать транзакцию.
+// Как и в Create, сохранённым заказам проставляется их статус в БД, а written
+// отмечает заказы, которые были вставлены или заменены.
+func (r *Repository) CreateBatch(ctx context.Context, orders []*models.OrderJSON) ([]bool, []error, error) {
+	written := make([]bool, len(orders))
+	results := make([]error, len(orders))
+	if len(orders) == 0 {
+		return written, results, nil
+	}
+
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		return copyOrders(ctx, tx, orders)
+	})
+	if err == nil {
+		for i, orderJSON := range orders {
+			orderJSON.Status = models.StatusCreated
+			written[i] = true
+		}
+		r.logger.Infof("Repository.CreateBatch: %d orders copied", len(orders))
+		return written, results, nil
+	}
+	r.logger.Warnf("Repository.CreateBatch: bulk copy failed, falling back to per-order inserts: %v", err)
+
+	statuses := make([]models.OrderStatus, len(orders))
+	err = r.inTx(ctx, func(tx pgx.Tx) error {
+		for i, orderJSON := range orders {
+			if _, err := tx.Exec(ctx, "SAVEPOINT batch_order"); err != nil {
+				return err
+			}
+			if statuses[i], written[i], results[i] = r.writeOrder(ctx, tx, orderJSON); results[i] != nil {
+				if _, err := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT batch_order"); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := tx.Exec(ctx, "RELEASE SAVEPOINT batch_order"); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Repository.CreateBatch: %w", classifyError(err))
+	}
+	for i, orderJSON := range orders {
+		if results[i] == nil {
+			orderJSON.Status = statuses[i]
+		}
+	}
+	return written, results, nil
+}
 
+// inTx выполняет fn в транзакции и фиксирует её, если fn завершилась без ошибки
+func (r *Repository) inTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
 	tx, err := r.client.Begin(ctx)
 	if err != nil {
 		return err
 	}
-
 	defer func() {
 		if err := tx.Rollback(ctx); err != nil && err != pgx.ErrTxClosed {
 			r.logger.Errorf("failed to rollback transaction: %v", err)
 		}
 	}()
 
-	order := models.Order{
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// writeOrder записывает заказ со всеми связанными сущностями в рамках транзакции tx
+// и возвращает статус, который заказ имеет в БД, и признак того, что заказ был записан:
+// false означает, что сохранённый заказ совпадает с новым и запись пропущена
+func (r *Repository) writeOrder(ctx context.Context, tx pgx.Tx, orderJSON *models.OrderJSON) (models.OrderStatus, bool, error) {
+	hash, err := contentHash(orderJSON)
+	if err != nil {
+		return "", false, err
+	}
+	order := toOrderRow(orderJSON)
+
+	inserted, err := insertOrder(ctx, tx, order, hash)
+	if err != nil {
+		err = classifyError(err)
+		if errors.Is(err, ErrDuplicate) {
+			r.logger.Warnf("Repository.writeOrder: track number already used: %v", err)
+			return "", false, fmt.Errorf("%w: order with track number %s already exists", ErrDuplicate, orderJSON.TrackNumber)
+		}
+		r.logger.Warnf("Repository.writeOrder: %v", err)
+		return "", false, fmt.Errorf("failed to insert order: %w", err)
+	}
+	status := models.StatusCreated
+	if inserted {
+		if err := insertStatusChange(ctx, tx, orderJSON.OrderUID, "", models.StatusCreated, ""); err != nil {
+			r.logger.Warnf("Repository.writeOrder: %v", err)
+			return "", false, fmt.Errorf("failed to insert status history: %w", classifyError(err))
+		}
+	} else {
+		var replace bool
+		replace, status, err = r.resolveDuplicate(ctx, tx, orderJSON, order, hash)
+		if err != nil || !replace {
+			return status, false, err
+		}
+	}
+
+	delivery := orderJSON.Delivery
+	delivery.OrderUID = orderJSON.OrderUID
+	if err := insertDelivery(ctx, tx, delivery); err != nil {
+		r.logger.Warnf("Repository.writeOrder: %v", err)
+		return "", false, fmt.Errorf("failed to insert delivery: %w", classifyError(err))
+	}
+	if err := insertPayment(ctx, tx, orderJSON.Payment); err != nil {
+		r.logger.Warnf("Repository.writeOrder: %v", err)
+		return "", false, fmt.Errorf("failed to insert payment: %w", classifyError(err))
+	}
+	if err := insertItems(ctx, tx, orderJSON.Items); err != nil {
+		r.logger.Warnf("Repository.writeOrder: %v", err)
+		return "", false, fmt.Errorf("failed to insert items: %w", classifyError(err))
+	}
+
+	eventType := models.EventOrderUpdated
+	if inserted {
+		eventType = models.EventOrderCreated
+	}
+	if err := insertOutboxEvent(ctx, tx, newOrderEvent(eventType, orderJSON, status)); err != nil {
+		r.logger.Warnf("Repository.writeOrder: %v", err)
+		return "", false, fmt.Errorf("failed to insert outbox event: %w", classifyError(err))
+	}
+	return status, true, nil
+}
+
+// resolveDuplicate применяет политику дубликатов к заказу, UID которого уже есть в БД.
+// Возвращает true, если заказ обновлён и его доставку, оплату и товары нужно записать заново,
+// и false, если сохранённый заказ совпадает с новым и запись не нужна.
+// Статус сохранённого заказа при этом не меняется и возвращается вторым значением.
+func (r *Repository) resolveDuplicate(ctx context.Context, tx pgx.Tx, orderJSON *models.OrderJSON, order models.Order, hash string) (bool, models.OrderStatus, error) {
+	policy := string(r.duplicatePolicy)
+	if r.duplicatePolicy == DuplicateReject {
+		metrics.OrderDuplicatesTotal.WithLabelValues(policy, "rejected").Inc()
+		return false, "", fmt.Errorf("%w: order with UID %s already exists", ErrDuplicate, order.OrderUID)
+	}
+
+	storedHash, storedTrack, status, err := selectOrderForUpdate(ctx, tx, order.OrderUID)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to read existing order: %w", classifyError(err))
+	}
+	if storedHash == "" {
+		if storedHash, err = r.backfillContentHash(ctx, tx, orderJSON, hash); err != nil {
+			return false, "", fmt.Errorf("failed to hash existing order: %w", classifyError(err))
+		}
+	}
+	if storedHash == hash {
+		metrics.OrderDuplicatesTotal.WithLabelValues(policy, "skipped").Inc()
+		r.logger.Infof("Repository.writeOrder: order %s is already stored, skipping", order.OrderUID)
+		return false, status, nil
+	}
+	if r.duplicatePolicy != DuplicateUpsert {
+		metrics.OrderDuplicatesTotal.WithLabelValues(policy, "rejected").Inc()
+		return false, "", fmt.Errorf("%w: order with UID %s already exists with different content", ErrDuplicate, order.OrderUID)
+	}
+
+	if err := deleteOrderDetails(ctx, tx, order.OrderUID, storedTrack); err != nil {
+		return false, "", fmt.Errorf("failed to delete order details: %w", classifyError(err))
+	}
+	if err := updateOrder(ctx, tx, order, hash); err != nil {
+		return false, "", fmt.Errorf("failed to update order: %w", classifyError(err))
+	}
+	metrics.OrderDuplicatesTotal.WithLabelValues(policy, "replaced").Inc()
+	r.logger.Infof("Repository.writeOrder: order %s replaced", order.OrderUID)
+	return true, status, nil
+}
+
+// backfillContentHash сравнивает новый заказ с заказом, сохранённым до появления колонки
+// content_hash, по их содержимому в БД. Если они совпадают, сохранённому заказу записывается
+// хэш hash нового и он возвращается; иначе возвращается пустой хэш.
+func (r *Repository) backfillContentHash(ctx context.Context, tx pgx.Tx, orderJSON *models.OrderJSON, hash string) (string, error) {
+	stored, err := loadOrders(ctx, tx, []string{orderJSON.OrderUID})
+	if err != nil {
+		return "", err
+	}
+	if len(stored) == 0 {
+		return "", fmt.Errorf("%w: order %s", ErrNotFound, orderJSON.OrderUID)
+	}
+	storedHash, err := storedContentHash(&stored[0])
+	if err != nil {
+		return "", err
+	}
+	newHash, err := storedContentHash(orderJSON)
+	if err != nil {
+		return "", err
+	}
+	if storedHash != newHash {
+		return "", nil
+	}
+	if err := setContentHash(ctx, tx, orderJSON.OrderUID, hash); err != nil {
+		return "", err
+	}
+	r.logger.Infof("Repository.writeOrder: content hash of order %s backfilled", orderJSON.OrderUID)
+	return hash, nil
+}
+
+func toOrderRow(orderJSON *models.OrderJSON) models.Order {
+	return models.Order{
 		OrderUID:          orderJSON.OrderUID,
 		TrackNumber:       orderJSON.TrackNumber,
 		Entry:             orderJSON.Entry,
@@ -68,90 +292,66 @@ func (r *Repository) Create(ctx context.Context, orderJSON *models.OrderJSON) er
 		DateCreated:       orderJSON.DateCreated,
 		OofShard:          orderJSON.OofShard,
 	}
-	r.logger.Infof("Repository.Create: Transaction BEGIN for %s", order.OrderUID)
+}
 
-	if err = insertOrder(ctx, tx, order); err != nil {
-		if isDuplicateKeyError(err) {
-			r.logger.Warnf("Repository.Create: order already exists: %v", err)
-			return fmt.Errorf("%w: order with UID %s already exists", errExist, orderJSON.OrderUID)
-		}
-		r.logger.Warnf("Repository.Create: %v", err)
-		return fmt.Errorf("failed to insert order: %w", err)
+// ListOrders возвращает заказы, подходящие под фильтр, в порядке убывания date_created
+func (r *Repository) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.OrderJSON, error) {
+	orderUIDs, err := r.listOrderUIDs(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	delivery := orderJSON.Delivery
-	delivery.OrderUID = orderJSON.OrderUID
-	if err = insertDelivery(ctx, tx, delivery); err != nil {
-		r.logger.Warnf("Repository.Create: %v", err)
-		return fmt.Errorf("failed to insert delivery: %w", err)
-	}
-	payment := orderJSON.Payment
-	if err = insertPayment(ctx, tx, payment); err != nil {
-		r.logger.Warnf("Repository.Create: %v", err)
-		return fmt.Errorf("failed to insert payment: %w", err)
-	}
-	items := orderJSON.Items
-	for _, item := range items {
-		if err = insertItems(ctx, tx, item); err != nil {
-			r.logger.Warnf("Repository.Create: %v", err)
-			return fmt.Errorf("failed to insert item: %w", err)
-		}
+	orders, err := loadOrders(ctx, r.client, orderUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("Repository.ListOrders: %w", classifyError(err))
 	}
-	r.logger.Info("Repository.Create: Transaction COMMIT")
-	return tx.Commit(ctx)
+	return orders, nil
 }
 
-// GetAll возвращает все заказы из базы данных
-func (r *Repository) GetAll(ctx context.Context) ([]models.OrderJSON, error) {
-	orderUIDs, err := r.getAllOrderUIDs(ctx)
+func (r *Repository) listOrderUIDs(ctx context.Context, filter models.OrderFilter) ([]string, error) {
+	query, args := buildListOrdersQuery(filter)
+	rows, err := r.client.Query(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("Repository.listOrderUIDs: %w", classifyError(err))
 	}
-	if len(orderUIDs) == 0 {
-		return []models.OrderJSON{}, nil
-	}
-	var orders []models.OrderJSON
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	errCh := make(chan error, len(orderUIDs))
-	semaphore := make(chan struct{}, 10)
-
-	for _, orderUID := range orderUIDs {
-		wg.Add(1)
-		go func(UID string) {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() {
-				<-semaphore
-			}()
-			order, err := r.GetOrder(ctx, UID)
-			if err != nil {
-				errCh <- fmt.Errorf("failed to get order %s: %w", UID, err)
-				return
-			}
-			mu.Lock()
-			orders = append(orders, *order)
-			mu.Unlock()
-		}(orderUID)
-	}
-	wg.Wait()
-	close(errCh)
+	defer rows.Close()
 
-	if len(errCh) > 0 {
-		r.logger.Warnf("Some orders failed to load: %d errors", len(errCh))
+	var orderUIDs []string
+	for rows.Next() {
+		var orderUID string
+		if err := rows.Scan(&orderUID); err != nil {
+			return nil, fmt.Errorf("Repository.listOrderUIDs: %w", classifyError(err))
+		}
+		orderUIDs = append(orderUIDs, orderUID)
 	}
-
-	return orders, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Repository.listOrderUIDs: %w", classifyError(err))
+	}
+	return orderUIDs, nil
 }
 
-func (r *Repository) getAllOrderUIDs(ctx context.Context) ([]string, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+// GetOrderUIDByTrackNumber возвращает UID заказа по его трек-номеру
+func (r *Repository) GetOrderUIDByTrackNumber(ctx context.Context, trackNumber string) (string, error) {
+	var orderUID string
+	err := r.client.QueryRow(ctx,
+		`SELECT order_uid FROM orders WHERE track_number = $1`,
+		trackNumber).Scan(&orderUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			r.logger.Warnf("Repository.GetOrderUIDByTrackNumber: track number %s not found", trackNumber)
+			return "", fmt.Errorf("%w: track number %s", ErrNotFound, trackNumber)
+		}
+		return "", fmt.Errorf("Repository.GetOrderUIDByTrackNumber: %w", classifyError(err))
+	}
+	return orderUID, nil
+}
 
-	rows, err := r.client.Query(ctx, "SELECT order_uid FROM orders ORDER BY date_created DESC")
+// GetOrderUIDsByItem возвращает UID заказов, содержащих товар с указанными chrt_id и/или nm_id
+func (r *Repository) GetOrderUIDsByItem(ctx context.Context, chrtID, nmID int64) ([]string, error) {
+	query, args := buildOrdersByItemQuery(chrtID, nmID)
+	rows, err := r.client.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("Repository.getAllOrderUIDs: %w", err)
+		return nil, fmt.Errorf("Repository.GetOrderUIDsByItem: %w", classifyError(err))
 	}
 	defer rows.Close()
 
@@ -159,142 +359,100 @@ func (r *Repository) getAllOrderUIDs(ctx context.Context) ([]string, error) {
 	for rows.Next() {
 		var orderUID string
 		if err := rows.Scan(&orderUID); err != nil {
-			return nil, fmt.Errorf("Repository.getAllOrderUIDs: %w", err)
+			return nil, fmt.Errorf("Repository.GetOrderUIDsByItem: %w", classifyError(err))
 		}
 		orderUIDs = append(orderUIDs, orderUID)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("Repository.getAllOrderUIDs: %w", err)
+		return nil, fmt.Errorf("Repository.GetOrderUIDsByItem: %w", classifyError(err))
 	}
 	return orderUIDs, nil
 }
 
 // GetOrder возвращает заказ по его UID из базы данных
 func (r *Repository) GetOrder(ctx context.Context, orderUID string) (*models.OrderJSON, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	var order models.OrderJSON
-	err := r.client.QueryRow(ctx,
-		`SELECT order_uid, track_number, entry, locale, internal_signature, customer_id, delivery_service, shardkey, sm_id, date_created, oof_shard
-		FROM orders
-		WHERE order_uid = $1`,
-		orderUID).Scan(
-		&order.OrderUID,
-		&order.TrackNumber,
-		&order.Entry,
-		&order.Locale,
-		&order.InternalSignature,
-		&order.CustomerID,
-		&order.DeliveryService,
-		&order.ShardKey,
-		&order.SmID,
-		&order.DateCreated,
-		&order.OofShard,
-	)
+	orders, err := loadOrders(ctx, r.client, []string{orderUID})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			r.logger.Warnf("Repository.GetOrder: order %s not found", orderUID)
-			return nil, fmt.Errorf("%w: order %s", errNotFound, orderUID)
-		}
 		r.logger.Warnf("Repository.GetOrder: failed to get order: %v", err)
-		return nil, fmt.Errorf("failed to get order: %w", err)
-	}
-	var delivery models.Delivery
-	err = r.client.QueryRow(ctx,
-		`SELECT order_uid, name, phone, zip, city, address, region, email
-		FROM deliveries
-		WHERE order_uid = $1`,
-		orderUID).Scan(
-		&delivery.OrderUID,
-		&delivery.Name,
-		&delivery.Phone,
-		&delivery.Zip,
-		&delivery.City,
-		&delivery.Address,
-		&delivery.Region,
-		&delivery.Email,
-	)
+		return nil, fmt.Errorf("failed to get order: %w", classifyError(err))
+	}
+	if len(orders) == 0 {
+		r.logger.Warnf("Repository.GetOrder: order %s not found", orderUID)
+		return nil, fmt.Errorf("%w: order %s", ErrNotFound, orderUID)
+	}
+	return &orders[0], nil
+}
+
+// GetOrderStatus возвращает текущий статус заказа
+func (r *Repository) GetOrderStatus(ctx context.Context, orderUID string) (models.OrderStatus, error) {
+	var status models.OrderStatus
+	err := r.client.QueryRow(ctx, `SELECT status FROM orders WHERE order_uid = $1`, orderUID).Scan(&status)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			r.logger.Warnf("Repository.GetOrder: %v", err)
-			return nil, fmt.Errorf("delivery not found: %w", err)
+			return "", fmt.Errorf("%w: order %s", ErrNotFound, orderUID)
 		}
-		r.logger.Warnf("Repository.GetOrder: %v", err)
-		return nil, fmt.Errorf("failed to get delivery: %w", err)
-	}
-	var payment models.Payment
-	err = r.client.QueryRow(ctx,
-		`SELECT transaction, request_id, currency, provider, amount, payment_dt, bank, delivery_cost, goods_total, custom_fee
-		FROM payments
-		WHERE transaction = $1`,
-		orderUID).Scan(
-		&payment.Transaction,
-		&payment.RequestID,
-		&payment.Currency,
-		&payment.Provider,
-		&payment.Amount,
-		&payment.PaymentDT,
-		&payment.Bank,
-		&payment.DeliveryCost,
-		&payment.GoodsTotal,
-		&payment.CustomFee,
-	)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			r.logger.Warnf("Repository.GetOrder: %v", err)
-			return nil, fmt.Errorf("payment not found: %w", err)
+		return "", fmt.Errorf("Repository.GetOrderStatus: %w", classifyError(err))
+	}
+	return status, nil
+}
+
+// UpdateStatus переводит заказ из статуса from в статус to и записывает переход в историю.
+// Если текущий статус заказа уже не from, возвращается errStatusChanged.
+func (r *Repository) UpdateStatus(ctx context.Context, orderUID string, from, to models.OrderStatus, reason string) error {
+	err := r.inTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE orders SET status = $3 WHERE order_uid = $1 AND status = $2`,
+			orderUID, string(from), string(to))
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return errStatusChanged
 		}
-		r.logger.Warnf("Repository.GetOrder: %v", err)
-		return nil, fmt.Errorf("failed to get payment: %w", err)
-	}
-	var items []models.Item
-	rows, err := r.client.Query(ctx,
-		`SELECT chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status
-		FROM items
-		WHERE track_number = $1`,
-		order.TrackNumber)
+		if err := insertStatusChange(ctx, tx, orderUID, from, to, reason); err != nil {
+			return err
+		}
+		return insertOutboxEvent(ctx, tx, models.OrderEvent{
+			Type:           models.EventOrderUpdated,
+			OrderUID:       orderUID,
+			Status:         to,
+			PreviousStatus: from,
+			Reason:         reason,
+			OccurredAt:     time.Now().UTC(),
+		})
+	})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			r.logger.Warnf("Repository.GetOrder: %v", err)
-			return nil, fmt.Errorf("items not found: %w", err)
+		if errors.Is(err, errStatusChanged) {
+			return err
 		}
-		r.logger.Warnf("Repository.GetOrder: %v", err)
-		return nil, fmt.Errorf("failed to get items: %w", err)
+		return fmt.Errorf("Repository.UpdateStatus: %w", classifyError(err))
 	}
-	for rows.Next() {
-		var item models.Item
-		if err := rows.Scan(
-			&item.ChrtID,
-			&item.TrackNumber,
-			&item.Price,
-			&item.RID,
-			&item.Name,
-			&item.Sale,
-			&item.Size,
-			&item.TotalPrice,
-			&item.NmID,
-			&item.Brand,
-			&item.Status,
-		); err != nil {
-			r.logger.Warnf("Repository.GetOrder: %v", err)
-			return nil, fmt.Errorf("failed to scan item: %w", err)
-		}
-		items = append(items, item)
+	r.logger.Infof("Repository.UpdateStatus: order %s %s -> %s", orderUID, from, to)
+	return nil
+}
+
+// GetStatusHistory возвращает историю статусов заказа в хронологическом порядке
+func (r *Repository) GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	rows, err := r.client.Query(ctx, `
+		SELECT COALESCE(from_status, ''), to_status, reason, changed_at
+		FROM order_status_history
+		WHERE order_uid = $1
+		ORDER BY id`, orderUID)
+	if err != nil {
+		return nil, fmt.Errorf("Repository.GetStatusHistory: %w", classifyError(err))
 	}
 	defer rows.Close()
 
-	order.Delivery = delivery
-	order.Payment = payment
-	order.Items = items
-
-	return &order, err
-}
-
-func isDuplicateKeyError(err error) bool {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		return pgErr.Code == "23505"
+	history := []models.StatusChange{}
+	for rows.Next() {
+		var change models.StatusChange
+		if err := rows.Scan(&change.From, &change.To, &change.Reason, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("Repository.GetStatusHistory: %w", classifyError(err))
+		}
+		history = append(history, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("Repository.GetStatusHistory: %w", classifyError(err))
 	}
-	return false
+	return history, nil
 }
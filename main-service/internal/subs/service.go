@@ -2,27 +2,59 @@ package subs
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"orders/internal/metrics"
 	"orders/pkg/models"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
 )
 
+// orderLoadTimeout ограничивает загрузку заказа из БД, общую для нескольких запросов
+const orderLoadTimeout = 5 * time.Second
+
 // Service содержит бизнес-логику для работы с заказами
 type Service struct {
 	repo   OrderRepository
 	cache  Cache
 	logger *logrus.Logger
+	// cachePolicy определяет, как кэш обновляется при записи заказов
+	cachePolicy CachePolicy
+	// loads объединяет одновременные загрузки одного заказа из БД
+	loads singleflight.Group
+	// generations не даёт загрузке из БД перезаписать в кэше заказ, записанный во время загрузки
+	generations orderGenerations
+	// warmUp хранит ход прогрева кэша для проверки готовности
+	warmUp atomic.Pointer[WarmUpProgress]
+	// newOrders рассылает сохранённые заказы подписчикам
+	newOrders *orderBroker
+	// writes — очередь фоновой записи заказов при политике write-behind
+	writes chan *models.OrderJSON
+	// pendingWrites учитывает заказы, ещё не записанные в фоне
+	pendingWrites sync.WaitGroup
+	// onWriteFailure сообщает о заказах, которые не удалось записать в фоне
+	onWriteFailure WriteFailureHandler
 }
 
-// NewService создает новый экземпляр Service
-func NewService(repo OrderRepository, logger *logrus.Logger, cache Cache) *Service {
+// NewService создает новый экземпляр Service.
+// При политике write-behind заказы записываются в БД только после запуска RunWriteBehind.
+func NewService(repo OrderRepository, logger *logrus.Logger, cache Cache, cachePolicy CachePolicy) *Service {
 	service := &Service{
-		repo:   repo,
-		cache:  cache,
-		logger: logger,
+		repo:        repo,
+		cache:       cache,
+		logger:      logger,
+		cachePolicy: cachePolicy,
+		newOrders:   newOrderBroker(),
+	}
+	if cachePolicy == CacheWriteBehind {
+		service.writes = make(chan *models.OrderJSON, writeBehindQueueSize)
 	}
+	service.warmUp.Store(&WarmUpProgress{Status: WarmUpWarming})
 
 	return service
 }
@@ -32,45 +64,324 @@ func (s *Service) Create(ctx context.Context, orderJSON *models.OrderJSON) error
 	timer := prometheus.NewTimer(metrics.OrderProcessingDuration.WithLabelValues("create"))
 	defer timer.ObserveDuration()
 
-	err := s.repo.Create(ctx, orderJSON)
+	if s.cachePolicy == CacheWriteBehind {
+		return s.createBehind(ctx, orderJSON)
+	}
+	written, err := s.repo.Create(ctx, orderJSON)
 	if err != nil {
 		metrics.OrdersCreatedTotal.WithLabelValues("error").Inc()
 		return err
 	}
 
 	metrics.OrdersCreatedTotal.WithLabelValues("success").Inc()
-	s.cache.Delete(orderJSON.OrderUID)
+	s.updateCache(orderJSON)
+	// Повторно доставленный заказ, совпадающий с сохранённым, подписчикам уже отправлялся
+	if written {
+		s.newOrders.publish(orderJSON)
+	}
 
 	return nil
 }
 
-// GetOrder возвращает заказ по его UID
+// CreateBatch сохраняет пачку заказов и возвращает ошибку для каждого заказа.
+// Общая ошибка означает, что не был сохранён ни один заказ.
+func (s *Service) CreateBatch(ctx context.Context, orders []*models.OrderJSON) ([]error, error) {
+	timer := prometheus.NewTimer(metrics.OrderProcessingDuration.WithLabelValues("create_batch"))
+	defer timer.ObserveDuration()
+
+	if s.cachePolicy == CacheWriteBehind {
+		results := make([]error, len(orders))
+		for i, order := range orders {
+			if results[i] = s.createBehind(ctx, order); results[i] != nil {
+				return nil, results[i]
+			}
+		}
+		return results, nil
+	}
+
+	written, results, err := s.repo.CreateBatch(ctx, orders)
+	if err != nil {
+		metrics.OrdersCreatedTotal.WithLabelValues("error").Add(float64(len(orders)))
+		return nil, err
+	}
+
+	for i, err := range results {
+		if err != nil {
+			metrics.OrdersCreatedTotal.WithLabelValues("error").Inc()
+			continue
+		}
+		metrics.OrdersCreatedTotal.WithLabelValues("success").Inc()
+		s.updateCache(orders[i])
+		if written[i] {
+			s.newOrders.publish(orders[i])
+		}
+	}
+	return results, nil
+}
+
+// SubscribeNewOrders подписывает на заказы, сохранённые этой репликой после подписки
+// и подходящие под filter. Подписка действует до отмены ctx. Если подписчик не успевает
+// читать и буфер заполнен, новые заказы для него пропускаются.
+func (s *Service) SubscribeNewOrders(ctx context.Context, filter SubscriptionFilter, buffer int) *Subscription {
+	return s.newOrders.subscribe(ctx, filter, buffer)
+}
+
+// updateCache обновляет кэш после успешной записи заказа согласно политике кэширования.
+// Записи индекса по трек-номеру и товарам заказа удаляются при любой политике:
+// иначе поиск по ним не находил бы новый заказ до истечения TTL.
+func (s *Service) updateCache(order *models.OrderJSON) {
+	switch s.cachePolicy {
+	case CacheWriteThrough, CacheWriteBehind:
+		s.cacheOrder(order)
+	default:
+		s.invalidateOrder(order)
+	}
+}
+
+// cacheOrder кладёт заказ в кэш и удаляет записи индекса по его идентификаторам
+func (s *Service) cacheOrder(order *models.OrderJSON) {
+	s.cache.DeleteIndex(orderIndexKeys(order))
+	s.storeOrder(order)
+}
+
+// invalidateOrder удаляет заказ и записи индекса по его идентификаторам из кэша
+func (s *Service) invalidateOrder(order *models.OrderJSON) {
+	s.generations.Bump(order.OrderUID)
+	s.cache.DeleteIndex(orderIndexKeys(order))
+	s.cache.Delete(order.OrderUID)
+}
+
+// storeOrder заменяет заказ в кэше. Delete перед Set сбрасывает копии заказа
+// в локальных кэшах других реплик.
+func (s *Service) storeOrder(order *models.OrderJSON) {
+	s.generations.Bump(order.OrderUID)
+	s.cache.Delete(order.OrderUID)
+	s.cache.Set(order.OrderUID, order)
+}
+
+// ChangeStatus переводит заказ в статус to, если переход разрешён.
+// Повторная установка текущего статуса не считается ошибкой.
+func (s *Service) ChangeStatus(ctx context.Context, orderUID string, to models.OrderStatus, reason string) error {
+	timer := prometheus.NewTimer(metrics.OrderProcessingDuration.WithLabelValues("change_status"))
+	defer timer.ObserveDuration()
+
+	if !to.IsValid() {
+		return &ValidationError{Fields: []FieldError{{Field: "status", Message: fmt.Sprintf("unknown status %q", to)}}}
+	}
+
+	for attempt := 1; attempt <= statusUpdateAttempts; attempt++ {
+		from, err := s.repo.GetOrderStatus(ctx, orderUID)
+		if err != nil {
+			return err
+		}
+		if from == to {
+			s.logger.Infof("Service.ChangeStatus: order %s already has status %s", orderUID, to)
+			return nil
+		}
+		if !CanTransition(from, to) {
+			return fmt.Errorf("%w: order %s cannot move from %s to %s", ErrInvalidTransition, orderUID, from, to)
+		}
+
+		err = s.repo.UpdateStatus(ctx, orderUID, from, to, reason)
+		if errors.Is(err, errStatusChanged) {
+			s.logger.Warnf("Service.ChangeStatus: order %s status changed concurrently, attempt %d", orderUID, attempt)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		metrics.OrderStatusTransitionsTotal.WithLabelValues(string(from), string(to)).Inc()
+		s.generations.Bump(orderUID)
+		s.cache.Delete(orderUID)
+		return nil
+	}
+	return fmt.Errorf("%w: status of order %s keeps changing concurrently", ErrTransient, orderUID)
+}
+
+// GetStatusHistory возвращает текущий статус заказа и историю его изменений
+func (s *Service) GetStatusHistory(ctx context.Context, orderUID string) (*models.StatusHistory, error) {
+	status, err := s.repo.GetOrderStatus(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.GetStatusHistory(ctx, orderUID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StatusHistory{
+		OrderUID: orderUID,
+		Status:   status,
+		History:  history,
+	}, nil
+}
+
+// GetOrder возвращает заказ по его UID.
+// Устаревший заказ отдаётся из кэша сразу и обновляется в фоне; одновременные промахи
+// по одному UID выполняют один запрос к БД, а отсутствие заказа запоминается в кэше.
 func (s *Service) GetOrder(ctx context.Context, orderUID string) (*models.OrderJSON, error) {
 	if order, found := s.cache.Get(orderUID); found {
+		metrics.CacheLookupsTotal.WithLabelValues("hit").Inc()
 		s.logger.Info("Service.GetOrder: Get Order From CACHE")
 		return order, nil
 	}
+	if s.cache.IsMissing(orderUID) {
+		metrics.CacheLookupsTotal.WithLabelValues("negative").Inc()
+		return nil, fmt.Errorf("%w: order %s", ErrNotFound, orderUID)
+	}
+	if order, found := s.cache.GetStale(orderUID); found {
+		metrics.CacheLookupsTotal.WithLabelValues("stale").Inc()
+		s.logger.Infof("Service.GetOrder: order %s is stale, refreshing in background", orderUID)
+		s.loads.DoChan(orderUID, s.loadOrder(context.Background(), orderUID))
+		return order, nil
+	}
+	metrics.CacheLookupsTotal.WithLabelValues("miss").Inc()
+
+	// Загрузка не зависит от отмены ctx: её результат может ждать несколько запросов
+	results := s.loads.DoChan(orderUID, s.loadOrder(context.WithoutCancel(ctx), orderUID))
+	select {
+	case res := <-results:
+		if res.Shared {
+			metrics.CacheSharedLoadsTotal.Inc()
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		order, _ := res.Val.(*models.OrderJSON)
+		return order, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// loadOrder возвращает функцию загрузки заказа из БД для singleflight.
+// Найденный заказ сохраняется в кэше, отсутствующий — отмечается в нём. Если заказ
+// записан во время загрузки, кэш не трогается: прочитанный заказ мог устареть.
+func (s *Service) loadOrder(ctx context.Context, orderUID string) func() (any, error) {
+	return func() (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, orderLoadTimeout)
+		defer cancel()
+
+		generation := s.generations.Current(orderUID)
+		order, err := s.repo.GetOrder(ctx, orderUID)
+		s.logger.Info("Service.GetOrder: Get order From db")
+		if err == nil && order == nil {
+			err = fmt.Errorf("%w: order %s", ErrNotFound, orderUID)
+		}
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				s.generations.IfCurrent(orderUID, generation, func() { s.cache.SetMissing(orderUID) })
+			}
+			s.logger.Errorf("Service.GetOrder: %v", err)
+			return nil, err
+		}
+		if !s.generations.IfCurrent(orderUID, generation, func() { s.cache.Set(orderUID, order) }) {
+			s.logger.Infof("Service.GetOrder: order %s was written while loading, not cached", orderUID)
+			return order, nil
+		}
+		s.logger.Info("Service.GetOrder: Get order From db and cached")
+		return order, nil
+	}
+}
+
+// GetOrderByTrackNumber возвращает заказ по трек-номеру.
+// Запись индекса, указывающая на заказ с другим трек-номером, устарела: трек-номер
+// изменён при замене заказа. Она удаляется, и заказ ищется в БД заново.
+func (s *Service) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.OrderJSON, error) {
+	key := trackNumberKey(trackNumber)
+	if orderUIDs, found := s.cache.GetIndex(key); found {
+		order, err := s.GetOrder(ctx, orderUIDs[0])
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if err == nil && order.TrackNumber == trackNumber {
+			return order, nil
+		}
+		s.logger.Infof("Service.GetOrderByTrackNumber: index entry %s is stale", key)
+		s.cache.DeleteIndex([]string{key})
+	}
 
-	order, err := s.repo.GetOrder(ctx, orderUID)
-	s.logger.Info("Service.GetOrder: Get order From db")
+	orderUID, err := s.repo.GetOrderUIDByTrackNumber(ctx, trackNumber)
 	if err != nil {
-		s.logger.Errorf("Service.GetOrder: %v", err)
+		s.logger.Errorf("Service.GetOrderByTrackNumber: %v", err)
 		return nil, err
 	}
-	if order == nil {
-		return nil, nil
+	s.cache.SetIndex(key, []string{orderUID})
+	return s.GetOrder(ctx, orderUID)
+}
+
+// GetOrdersByItem возвращает заказы, содержащие товар с указанными chrt_id и/или nm_id.
+// Заказы из индекса, в которых такого товара уже нет (товары изменены при замене заказа),
+// пропускаются, а устаревшая запись индекса удаляется.
+func (s *Service) GetOrdersByItem(ctx context.Context, chrtID, nmID int64) ([]models.OrderJSON, error) {
+	key := itemIndexKey(chrtID, nmID)
+	orderUIDs, found := s.cache.GetIndex(key)
+	if !found {
+		var err error
+		orderUIDs, err = s.repo.GetOrderUIDsByItem(ctx, chrtID, nmID)
+		if err != nil {
+			s.logger.Errorf("Service.GetOrdersByItem: %v", err)
+			return nil, err
+		}
+		s.cache.SetIndex(key, orderUIDs)
+	}
+
+	orders := make([]models.OrderJSON, 0, len(orderUIDs))
+	stale := false
+	for _, orderUID := range orderUIDs {
+		order, err := s.GetOrder(ctx, orderUID)
+		if errors.Is(err, ErrNotFound) {
+			// Заказ удалён после сохранения индекса
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !hasItem(order, chrtID, nmID) {
+			stale = true
+			continue
+		}
+		orders = append(orders, *order)
+	}
+	if stale {
+		s.logger.Infof("Service.GetOrdersByItem: index entry %s is stale", key)
+		s.cache.DeleteIndex([]string{key})
+	}
+	return orders, nil
+}
+
+// hasItem сообщает, есть ли в заказе товар с chrt_id и nm_id; нулевой идентификатор не проверяется
+func hasItem(order *models.OrderJSON, chrtID, nmID int64) bool {
+	for _, item := range order.Items {
+		if (chrtID == 0 || item.ChrtID == chrtID) && (nmID == 0 || item.NmID == nmID) {
+			return true
+		}
 	}
-	s.cache.Set(orderUID, order)
-	s.logger.Info("Service.GetOrder: Get order From db and cached")
-	return order, nil
+	return false
 }
 
-// WarmUpCache предзагружает данные в кэш при запуске сервиса
-func (s *Service) WarmUpCache(ctx context.Context) error {
-	orders, err := s.repo.GetAll(ctx)
+// ListOrders возвращает страницу заказов по фильтру с курсорной пагинацией
+func (s *Service) ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderPage, error) {
+	timer := prometheus.NewTimer(metrics.OrderProcessingDuration.WithLabelValues("list"))
+	defer timer.ObserveDuration()
+
+	limit := normalizeLimit(filter.Limit)
+	filter.Limit = limit + 1
+
+	orders, err := s.repo.ListOrders(ctx, filter)
 	if err != nil {
-		s.logger.Warnf("Service.WarmUpCache: %v", err)
-		return err
+		s.logger.Errorf("Service.ListOrders: %v", err)
+		return nil, err
+	}
+
+	page := &models.OrderPage{Orders: orders}
+	if len(orders) > limit {
+		page.Orders = orders[:limit]
+		last := page.Orders[limit-1]
+		page.NextCursor = EncodeCursor(models.Cursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID})
+	}
+	if page.Orders == nil {
+		page.Orders = []models.OrderJSON{}
 	}
-	return s.cache.WarmUpCache(orders)
+	return page, nil
 }
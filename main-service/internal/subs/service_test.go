@@ -2,14 +2,18 @@ package subs
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"orders/mocks"
 	"orders/pkg/models"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // TestService_Get тестирует получение данных из кэша
@@ -30,7 +34,7 @@ func TestService_Get(t *testing.T) {
 	mockRepo.AssertNotCalled(t, "GetOrder")
 
 	logger := getTestLogger()
-	service := NewService(mockRepo, logger, mockCache)
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
 
 	ctx := context.Background()
 	order, err := service.GetOrder(ctx, orderUID)
@@ -57,11 +61,13 @@ func TestService_GetFromDB(t *testing.T) {
 	}
 
 	mockCache.On("Get", orderUID).Return(nil, false).Once()
+	mockCache.On("IsMissing", orderUID).Return(false).Once()
+	mockCache.On("GetStale", orderUID).Return(nil, false).Once()
 	mockRepo.On("GetOrder", mock.Anything, orderUID).Return(order, nil).Once()
 	mockCache.On("Set", orderUID, order).Once()
 
 	logger := getTestLogger()
-	service := NewService(mockRepo, logger, mockCache)
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
 
 	ctx := context.Background()
 	result, err := service.GetOrder(ctx, orderUID)
@@ -80,11 +86,14 @@ func TestService_GetOrder_NotFound(t *testing.T) {
 	mockCache := &mocks.Cache{}
 
 	mockCache.On("Get", "missing").Return(nil, false)
+	mockCache.On("IsMissing", "missing").Return(false)
+	mockCache.On("GetStale", "missing").Return(nil, false)
+	mockCache.On("SetMissing", "missing").Once()
 	mockRepo.On("GetOrder", mock.Anything, "missing").
-		Return(nil, errors.New("not found"))
+		Return(nil, fmt.Errorf("%w: order missing", ErrNotFound))
 
 	logger := getTestLogger()
-	service := NewService(mockRepo, logger, mockCache)
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
 
 	ctx := context.Background()
 	order, err := service.GetOrder(ctx, "missing")
@@ -95,38 +104,211 @@ func TestService_GetOrder_NotFound(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-// TestService_WarmUpCache тестирует предзагрузку кэша
-func TestService_WarmUpCache(t *testing.T) {
+// TestService_GetOrder_NegativeCache тестирует отказ без запроса к БД для отсутствующего заказа
+func TestService_GetOrder_NegativeCache(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	mockCache.On("Get", "missing").Return(nil, false).Once()
+	mockCache.On("IsMissing", "missing").Return(true).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	order, err := service.GetOrder(context.Background(), "missing")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, order)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetOrder", mock.Anything, mock.Anything)
+}
+
+// TestService_GetOrder_Stale тестирует выдачу устаревшего заказа с обновлением в фоне
+func TestService_GetOrder_Stale(t *testing.T) {
 	mockRepo := &mocks.OrderRepository{}
 	mockCache := &mocks.Cache{}
 
 	orderUID := "test-123"
-	trackNumber := "WBILMTESTTRACK"
+	stale := &models.OrderJSON{OrderUID: orderUID, Status: models.StatusCreated}
+	fresh := &models.OrderJSON{OrderUID: orderUID, Status: models.StatusPaid}
 
-	order := &models.OrderJSON{
-		OrderUID:    orderUID,
-		TrackNumber: trackNumber,
+	refreshed := make(chan struct{})
+	mockCache.On("Get", orderUID).Return(nil, false).Once()
+	mockCache.On("IsMissing", orderUID).Return(false).Once()
+	mockCache.On("GetStale", orderUID).Return(stale, true).Once()
+	mockRepo.On("GetOrder", mock.Anything, orderUID).Return(fresh, nil).Once()
+	mockCache.On("Set", orderUID, fresh).Run(func(mock.Arguments) { close(refreshed) }).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	order, err := service.GetOrder(context.Background(), orderUID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, stale, order)
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("stale order was not refreshed")
 	}
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
 
-	orders := []models.OrderJSON{}
-	orders = append(orders, *order)
+// TestService_GetOrder_Coalesced тестирует, что одновременные промахи выполняют один запрос к БД
+func TestService_GetOrder_Coalesced(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
 
-	mockRepo.On("GetAll", mock.Anything).Return(orders, nil).Once()
-	mockCache.On("WarmUpCache", orders).Return(nil).Once()
+	orderUID := "test-123"
+	order := &models.OrderJSON{OrderUID: orderUID}
+	const requests = 10
+
+	var missed atomic.Int32
+	release := make(chan struct{})
+	mockCache.On("Get", orderUID).Return(nil, false)
+	mockCache.On("IsMissing", orderUID).Return(false)
+	mockCache.On("GetStale", orderUID).Run(func(mock.Arguments) { missed.Add(1) }).Return(nil, false)
+	mockRepo.On("GetOrder", mock.Anything, orderUID).
+		Run(func(mock.Arguments) { <-release }).
+		Return(order, nil).Once()
+	mockCache.On("Set", orderUID, order).Once()
 
-	logger := getTestLogger()
-	service := NewService(mockRepo, logger, mockCache)
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+
+	var wg sync.WaitGroup
+	results := make(chan *models.OrderJSON, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := service.GetOrder(context.Background(), orderUID)
+			assert.NoError(t, err)
+			results <- result
+		}()
+	}
+	// Даём всем запросам дойти до общей загрузки
+	assert.Eventually(t, func() bool { return missed.Load() == requests }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for result := range results {
+		assert.Equal(t, orderUID, result.OrderUID)
+	}
+	mockRepo.AssertNumberOfCalls(t, "GetOrder", 1)
+	mockCache.AssertExpectations(t)
+}
 
-	ctx := context.Background()
-	err := service.WarmUpCache(ctx)
+// TestService_GetOrder_NilOrder тестирует, что заказ, который репозиторий не вернул без ошибки,
+// считается не найденным и отмечается отсутствующим
+func TestService_GetOrder_NilOrder(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
 
-	assert.NoError(t, err)
+	mockCache.On("Get", "missing").Return(nil, false).Once()
+	mockCache.On("IsMissing", "missing").Return(false).Once()
+	mockCache.On("GetStale", "missing").Return(nil, false).Once()
+	mockRepo.On("GetOrder", mock.Anything, "missing").Return(nil, nil).Once()
+	mockCache.On("SetMissing", "missing").Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	order, err := service.GetOrder(context.Background(), "missing")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, order)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetOrder_WrittenWhileLoading тестирует, что загрузка не кладёт в кэш заказ,
+// прочитанный до записи, которая обновила кэш раньше завершения загрузки
+func TestService_GetOrder_WrittenWhileLoading(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	cache := NewLRUCache(CacheOptions{TTL: time.Minute}, getTestLogger())
+	service := NewService(mockRepo, getTestLogger(), cache, CacheWriteThrough)
+
+	orderUID := "test-123"
+	loaded := &models.OrderJSON{OrderUID: orderUID, Status: models.StatusCreated}
+	written := &models.OrderJSON{OrderUID: orderUID, Status: models.StatusPaid}
+	mockRepo.On("GetOrder", mock.Anything, orderUID).
+		Run(func(mock.Arguments) { service.updateCache(written) }).
+		Return(loaded, nil).Once()
+
+	order, err := service.GetOrder(context.Background(), orderUID)
+
+	require.NoError(t, err)
+	assert.Equal(t, loaded, order)
+	cached, found := cache.Get(orderUID)
+	require.True(t, found)
+	assert.Equal(t, models.StatusPaid, cached.Status)
+	mockRepo.AssertExpectations(t)
+}
 
+// TestService_WarmUpCache тестирует постраничную предзагрузку кэша
+func TestService_WarmUpCache(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	firstPage := []models.OrderJSON{
+		{OrderUID: "test-3", DateCreated: date.Add(2 * time.Hour)},
+		{OrderUID: "test-2", DateCreated: date.Add(time.Hour)},
+	}
+	secondPage := []models.OrderJSON{{OrderUID: "test-1", DateCreated: date}}
+
+	mockRepo.On("ListOrders", mock.Anything, models.OrderFilter{Limit: 2}).Return(firstPage, nil).Once()
+	mockRepo.On("ListOrders", mock.Anything, models.OrderFilter{
+		Limit:  1,
+		Cursor: &models.Cursor{DateCreated: date.Add(time.Hour), OrderUID: "test-2"},
+	}).Return(secondPage, nil).Once()
+	mockCache.On("WarmUpCache", firstPage).Return(2, nil).Once()
+	mockCache.On("WarmUpCache", secondPage).Return(1, nil).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	assert.Equal(t, WarmUpWarming, service.WarmUpProgress().Status)
+
+	err := service.WarmUpCache(context.Background(), WarmUpOptions{Limit: 3, PageSize: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, WarmUpProgress{Status: WarmUpReady, Loaded: 3}, service.WarmUpProgress())
 	mockCache.AssertExpectations(t)
 	mockRepo.AssertExpectations(t)
 }
 
-// TestService_Create тестирует создание записи в репозитории и удалении записи из кэша
+// TestService_WarmUpCache_StopsWhenFull тестирует остановку прогрева при заполнении кэша
+func TestService_WarmUpCache_StopsWhenFull(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	page := []models.OrderJSON{{OrderUID: "test-2"}, {OrderUID: "test-1"}}
+	mockRepo.On("ListOrders", mock.Anything, mock.MatchedBy(func(f models.OrderFilter) bool {
+		return f.Limit == 2 && !f.DateFrom.IsZero()
+	})).Return(page, nil).Once()
+	mockCache.On("WarmUpCache", page).Return(1, nil).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	err := service.WarmUpCache(context.Background(), WarmUpOptions{Window: time.Hour, PageSize: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, service.WarmUpProgress().Loaded)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_WarmUpCache_Fails тестирует состояние прогрева после ошибки БД
+func TestService_WarmUpCache_Fails(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	mockRepo.On("ListOrders", mock.Anything, mock.Anything).Return(nil, ErrTransient).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	err := service.WarmUpCache(context.Background(), WarmUpOptions{})
+
+	assert.ErrorIs(t, err, ErrTransient)
+	assert.Equal(t, WarmUpFailed, service.WarmUpProgress().Status)
+	mockCache.AssertNotCalled(t, "WarmUpCache", mock.Anything)
+}
+
+// TestService_Create тестирует создание записи в репозитории и удаление из кэша
+// заказа и записей индекса по его трек-номеру и товарам
 func TestService_Create(t *testing.T) {
 	mockRepo := &mocks.OrderRepository{}
 	mockCache := &mocks.Cache{}
@@ -137,13 +319,22 @@ func TestService_Create(t *testing.T) {
 	order := &models.OrderJSON{
 		OrderUID:    orderUID,
 		TrackNumber: trackNumber,
+		Items: []models.Item{
+			{ChrtID: 9934930, NmID: 2389212},
+			{ChrtID: 9934931, NmID: 2389212},
+		},
 	}
 
-	mockRepo.On("Create", mock.Anything, order).Return(nil).Once()
+	mockRepo.On("Create", mock.Anything, order).Return(true, nil).Once()
 	mockCache.On("Delete", orderUID).Once()
+	mockCache.On("DeleteIndex", []string{
+		"track:WBILMTESTTRACK",
+		"chrt:9934930", "nm:2389212", "chrt:9934930/nm:2389212",
+		"chrt:9934931", "chrt:9934931/nm:2389212",
+	}).Once()
 
 	logger := getTestLogger()
-	service := NewService(mockRepo, logger, mockCache)
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
 
 	ctx := context.Background()
 	err := service.Create(ctx, order)
@@ -154,7 +345,157 @@ func TestService_Create(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+// TestService_Create_WriteThrough тестирует запись заказа в кэш после сохранения в БД
+func TestService_Create_WriteThrough(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	order := &models.OrderJSON{OrderUID: "test-123"}
+
+	mockRepo.On("Create", mock.Anything, order).
+		Run(func(args mock.Arguments) { args.Get(1).(*models.OrderJSON).Status = models.StatusCreated }).
+		Return(true, nil).Once()
+	mockCache.On("Delete", "test-123").Once()
+	mockCache.On("DeleteIndex", mock.Anything).Once()
+	mockCache.On("Set", "test-123", mock.MatchedBy(func(o *models.OrderJSON) bool {
+		return o.Status == models.StatusCreated
+	})).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheWriteThrough)
+	err := service.Create(context.Background(), order)
+
+	assert.NoError(t, err)
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_Create_WriteBehind тестирует выдачу заказа из кэша до записи в БД
+// и обновление его статусом из БД после фоновой записи
+func TestService_Create_WriteBehind(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	cache := NewLRUCache(CacheOptions{TTL: time.Minute}, getTestLogger())
+	service := NewService(mockRepo, getTestLogger(), cache, CacheWriteBehind)
+
+	order := testOrder("test-123")
+	require.NoError(t, service.Create(context.Background(), &order))
+	assert.Equal(t, models.StatusCreated, order.Status)
+
+	// Заказ ещё не записан, но уже отдаётся из кэша без обращения к БД
+	cached, err := service.GetOrder(context.Background(), "test-123")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusCreated, cached.Status)
+
+	mockRepo.On("CreateBatch", mock.Anything, mock.MatchedBy(func(orders []*models.OrderJSON) bool {
+		return len(orders) == 1 && orders[0].OrderUID == "test-123"
+	})).Run(func(args mock.Arguments) {
+		args.Get(1).([]*models.OrderJSON)[0].Status = models.StatusPaid
+	}).Return([]bool{false}, []error{nil}, nil).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.RunWriteBehind(ctx)
+	require.NoError(t, service.Close(context.Background()))
+
+	cached, err = service.GetOrder(context.Background(), "test-123")
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, cached.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_Create_WriteBehindFails тестирует удаление заказа из кэша и передачу ошибки
+// обработчику, если фоновая запись не удалась
+func TestService_Create_WriteBehindFails(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	cache := NewLRUCache(CacheOptions{TTL: time.Minute}, getTestLogger())
+	service := NewService(mockRepo, getTestLogger(), cache, CacheWriteBehind)
+
+	var failed []string
+	var failures []error
+	service.SetWriteFailureHandler(func(order *models.OrderJSON, err error) {
+		failed = append(failed, order.OrderUID)
+		failures = append(failures, err)
+	})
+	mockRepo.On("CreateBatch", mock.Anything, mock.Anything).
+		Return([]bool{true, false}, []error{nil, assert.AnError}, nil).Once()
+
+	first, second := testOrder("test-1"), testOrder("test-2")
+	results, err := service.CreateBatch(context.Background(), []*models.OrderJSON{&first, &second})
+	require.NoError(t, err)
+	assert.Equal(t, []error{nil, nil}, results)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.RunWriteBehind(ctx)
+	require.NoError(t, service.Close(context.Background()))
+
+	_, found := cache.Get("test-1")
+	assert.True(t, found)
+	_, found = cache.Get("test-2")
+	assert.False(t, found)
+	assert.Equal(t, []string{"test-2"}, failed)
+	require.Len(t, failures, 1)
+	assert.ErrorIs(t, failures[0], assert.AnError)
+	mockRepo.AssertExpectations(t)
+}
+
 // TestService_Create тестирует ошибку при создание записи в репозитории
+func TestService_CreateBatch(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	orders := []*models.OrderJSON{
+		{OrderUID: "test-1", TrackNumber: "TRACK1"},
+		{OrderUID: "test-2", TrackNumber: "TRACK2"},
+	}
+
+	mockRepo.On("CreateBatch", mock.Anything, orders).Return([]bool{true, false}, []error{nil, ErrDuplicate}, nil).Once()
+	mockCache.On("Delete", "test-1").Once()
+	mockCache.On("DeleteIndex", []string{"track:TRACK1"}).Once()
+
+	logger := getTestLogger()
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
+
+	results, err := service.CreateBatch(context.Background(), orders)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.NoError(t, results[0])
+	assert.ErrorIs(t, results[1], ErrDuplicate)
+
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_Create_SkippedDuplicateNotPublished проверяет, что заказ, совпавший с сохранённым,
+// не рассылается подписчикам повторно
+func TestService_Create_SkippedDuplicateNotPublished(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	order := &models.OrderJSON{OrderUID: "test-123"}
+
+	mockRepo.On("Create", mock.Anything, order).Return(true, nil).Once()
+	mockRepo.On("Create", mock.Anything, order).Return(false, nil).Once()
+	mockRepo.On("CreateBatch", mock.Anything, []*models.OrderJSON{order}).Return([]bool{false}, []error{nil}, nil).Once()
+	mockCache.On("Delete", "test-123")
+	mockCache.On("DeleteIndex", mock.Anything)
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := service.SubscribeNewOrders(ctx, SubscriptionFilter{}, 3)
+
+	require.NoError(t, service.Create(context.Background(), order))
+	require.NoError(t, service.Create(context.Background(), order))
+	results, err := service.CreateBatch(context.Background(), []*models.OrderJSON{order})
+	require.NoError(t, err)
+	require.NoError(t, results[0])
+
+	assert.Equal(t, "test-123", (<-sub.Orders()).OrderUID)
+	assert.Empty(t, sub.Orders())
+	mockRepo.AssertExpectations(t)
+}
+
 func TestService_Create_DBFails(t *testing.T) {
 	mockRepo := &mocks.OrderRepository{}
 	mockCache := &mocks.Cache{}
@@ -162,13 +503,13 @@ func TestService_Create_DBFails(t *testing.T) {
 	order := &models.OrderJSON{OrderUID: "test-456"}
 
 	mockRepo.On("Create", mock.Anything, order).
-		Return(assert.AnError).
+		Return(false, assert.AnError).
 		Once()
 
 	mockCache.AssertNotCalled(t, "Delete")
 
 	logger := getTestLogger()
-	service := NewService(mockRepo, logger, mockCache)
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
 
 	ctx := context.Background()
 	err := service.Create(ctx, order)
@@ -178,8 +519,254 @@ func TestService_Create_DBFails(t *testing.T) {
 	mockCache.AssertExpectations(t)
 }
 
+// TestService_ListOrders тестирует постраничную выдачу заказов с курсором на следующую страницу
+func TestService_ListOrders(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	dateCreated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	orders := []models.OrderJSON{
+		{OrderUID: "test-003", DateCreated: dateCreated.Add(2 * time.Hour)},
+		{OrderUID: "test-002", DateCreated: dateCreated.Add(time.Hour)},
+		{OrderUID: "test-001", DateCreated: dateCreated},
+	}
+
+	mockRepo.On("ListOrders", mock.Anything, models.OrderFilter{Locale: "en", Limit: 3}).
+		Return(orders, nil).Once()
+
+	logger := getTestLogger()
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
+
+	page, err := service.ListOrders(context.Background(), models.OrderFilter{Locale: "en", Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Len(t, page.Orders, 2)
+	assert.NotEmpty(t, page.NextCursor)
+
+	cursor, err := DecodeCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-002", cursor.OrderUID)
+	assert.True(t, cursor.DateCreated.Equal(orders[1].DateCreated))
+
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ListOrders_LastPage тестирует последнюю страницу без курсора
+func TestService_ListOrders_LastPage(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	mockRepo.On("ListOrders", mock.Anything, models.OrderFilter{Limit: defaultListLimit + 1}).
+		Return(nil, nil).Once()
+
+	logger := getTestLogger()
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
+
+	page, err := service.ListOrders(context.Background(), models.OrderFilter{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, page.Orders)
+	assert.NotNil(t, page.Orders)
+	assert.Empty(t, page.NextCursor)
+
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetOrderByTrackNumber тестирует поиск по трек-номеру с сохранением ключа индекса в кэше
+func TestService_GetOrderByTrackNumber(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	orderUID := "test-123"
+	trackNumber := "WBILMTESTTRACK"
+	order := &models.OrderJSON{OrderUID: orderUID, TrackNumber: trackNumber}
+
+	mockCache.On("GetIndex", "track:"+trackNumber).Return(nil, false).Once()
+	mockRepo.On("GetOrderUIDByTrackNumber", mock.Anything, trackNumber).Return(orderUID, nil).Once()
+	mockCache.On("SetIndex", "track:"+trackNumber, []string{orderUID}).Once()
+	mockCache.On("Get", orderUID).Return(order, true).Once()
+
+	logger := getTestLogger()
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
+
+	result, err := service.GetOrderByTrackNumber(context.Background(), trackNumber)
+
+	assert.NoError(t, err)
+	assert.Equal(t, orderUID, result.OrderUID)
+
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetOrdersByItem_FromIndex тестирует поиск по nm_id без обращения к БД при попадании в индекс
+func TestService_GetOrdersByItem_FromIndex(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	first := &models.OrderJSON{OrderUID: "test-001", Items: []models.Item{{NmID: 2389212}}}
+	second := &models.OrderJSON{OrderUID: "test-002", Items: []models.Item{{NmID: 1}, {NmID: 2389212}}}
+
+	mockCache.On("GetIndex", "nm:2389212").Return([]string{"test-001", "test-002"}, true).Once()
+	mockCache.On("Get", "test-001").Return(first, true).Once()
+	mockCache.On("Get", "test-002").Return(second, true).Once()
+
+	logger := getTestLogger()
+	service := NewService(mockRepo, logger, mockCache, CacheInvalidate)
+
+	orders, err := service.GetOrdersByItem(context.Background(), 0, 2389212)
+
+	assert.NoError(t, err)
+	assert.Len(t, orders, 2)
+	mockRepo.AssertNotCalled(t, "GetOrderUIDsByItem", mock.Anything, mock.Anything, mock.Anything)
+
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetOrdersByItem_SkipsMissing тестирует, что заказы из индекса, которых уже нет,
+// пропускаются: отмеченный отсутствующим и не найденный при загрузке из БД
+func TestService_GetOrdersByItem_SkipsMissing(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	first := &models.OrderJSON{OrderUID: "test-001", Items: []models.Item{{ChrtID: 9934930}}}
+
+	mockCache.On("GetIndex", "chrt:9934930").Return([]string{"test-001", "test-002", "test-003"}, true).Once()
+	mockCache.On("Get", "test-001").Return(first, true).Once()
+	mockCache.On("Get", "test-002").Return(nil, false).Once()
+	mockCache.On("IsMissing", "test-002").Return(true).Once()
+	mockCache.On("Get", "test-003").Return(nil, false).Once()
+	mockCache.On("IsMissing", "test-003").Return(false).Once()
+	mockCache.On("GetStale", "test-003").Return(nil, false).Once()
+	mockRepo.On("GetOrder", mock.Anything, "test-003").Return(nil, nil).Once()
+	mockCache.On("SetMissing", "test-003").Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+
+	orders, err := service.GetOrdersByItem(context.Background(), 9934930, 0)
+
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "test-001", orders[0].OrderUID)
+
+	mockCache.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetOrderByTrackNumber_StaleIndex тестирует, что запись индекса, оставшаяся
+// от прежнего трек-номера заменённого заказа, не возвращает этот заказ
+func TestService_GetOrderByTrackNumber_StaleIndex(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	cache := NewLRUCache(CacheOptions{TTL: time.Minute}, getTestLogger())
+	service := NewService(mockRepo, getTestLogger(), cache, CacheInvalidate)
+
+	order := testOrder("test-123")
+	order.TrackNumber = "NEWTRACK"
+	cache.Set(order.OrderUID, &order)
+	cache.SetIndex(trackNumberKey("OLDTRACK"), []string{order.OrderUID})
+	mockRepo.On("GetOrderUIDByTrackNumber", mock.Anything, "OLDTRACK").Return("", ErrNotFound).Once()
+
+	_, err := service.GetOrderByTrackNumber(context.Background(), "OLDTRACK")
+
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, found := cache.GetIndex(trackNumberKey("OLDTRACK"))
+	assert.False(t, found)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetOrdersByItem_StaleIndex тестирует, что заказ, из которого товар удалён
+// при замене, не возвращается по записи индекса этого товара
+func TestService_GetOrdersByItem_StaleIndex(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	cache := NewLRUCache(CacheOptions{TTL: time.Minute}, getTestLogger())
+	service := NewService(mockRepo, getTestLogger(), cache, CacheInvalidate)
+
+	replaced, current := testOrder("test-001"), testOrder("test-002")
+	replaced.Items = []models.Item{{ChrtID: 1, NmID: 6}}
+	current.Items = []models.Item{{ChrtID: 2, NmID: 5}}
+	cache.Set(replaced.OrderUID, &replaced)
+	cache.Set(current.OrderUID, &current)
+	cache.SetIndex(itemIndexKey(0, 5), []string{"test-001", "test-002"})
+
+	orders, err := service.GetOrdersByItem(context.Background(), 0, 5)
+
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "test-002", orders[0].OrderUID)
+	_, found := cache.GetIndex(itemIndexKey(0, 5))
+	assert.False(t, found)
+}
+
 func getTestLogger() *logrus.Logger {
 	logger := logrus.New()
 	logger.SetLevel(logrus.FatalLevel)
 	return logger
 }
+
+func TestService_ChangeStatus(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	orderUID := "test-123"
+	mockRepo.On("GetOrderStatus", mock.Anything, orderUID).Return(models.StatusCreated, nil).Once()
+	mockRepo.On("UpdateStatus", mock.Anything, orderUID, models.StatusCreated, models.StatusPaid, "payment received").
+		Return(nil).Once()
+	mockCache.On("Delete", orderUID).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	err := service.ChangeStatus(context.Background(), orderUID, models.StatusPaid, "payment received")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
+
+func TestService_ChangeStatus_InvalidTransition(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	orderUID := "test-123"
+	mockRepo.On("GetOrderStatus", mock.Anything, orderUID).Return(models.StatusCreated, nil).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	err := service.ChangeStatus(context.Background(), orderUID, models.StatusShipped, "")
+
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	mockRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_ChangeStatus_SameStatus(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	orderUID := "test-123"
+	mockRepo.On("GetOrderStatus", mock.Anything, orderUID).Return(models.StatusPaid, nil).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	err := service.ChangeStatus(context.Background(), orderUID, models.StatusPaid, "")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_ChangeStatus_ConcurrentChange(t *testing.T) {
+	mockRepo := &mocks.OrderRepository{}
+	mockCache := &mocks.Cache{}
+
+	orderUID := "test-123"
+	mockRepo.On("GetOrderStatus", mock.Anything, orderUID).Return(models.StatusCreated, nil).Once()
+	mockRepo.On("UpdateStatus", mock.Anything, orderUID, models.StatusCreated, models.StatusCancelled, "").
+		Return(errStatusChanged).Once()
+	mockRepo.On("GetOrderStatus", mock.Anything, orderUID).Return(models.StatusPaid, nil).Once()
+	mockRepo.On("UpdateStatus", mock.Anything, orderUID, models.StatusPaid, models.StatusCancelled, "").
+		Return(nil).Once()
+	mockCache.On("Delete", orderUID).Once()
+
+	service := NewService(mockRepo, getTestLogger(), mockCache, CacheInvalidate)
+	err := service.ChangeStatus(context.Background(), orderUID, models.StatusCancelled, "")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+}
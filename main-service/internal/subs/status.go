@@ -0,0 +1,26 @@
+package subs
+
+import "orders/pkg/models"
+
+// statusTransitions перечисляет допустимые переходы между статусами заказа.
+// cancelled и returned — конечные статусы.
+var statusTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.StatusCreated:    {models.StatusPaid, models.StatusCancelled},
+	models.StatusPaid:       {models.StatusAssembling, models.StatusCancelled},
+	models.StatusAssembling: {models.StatusShipped, models.StatusCancelled},
+	models.StatusShipped:    {models.StatusDelivered, models.StatusReturned},
+	models.StatusDelivered:  {models.StatusReturned},
+}
+
+// statusUpdateAttempts ограничивает число попыток смены статуса при конкурентных изменениях
+const statusUpdateAttempts = 3
+
+// CanTransition сообщает, разрешён ли переход заказа из статуса from в статус to
+func CanTransition(from, to models.OrderStatus) bool {
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package subs
+
+import (
+	"orders/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to models.OrderStatus
+		allowed  bool
+	}{
+		{models.StatusCreated, models.StatusPaid, true},
+		{models.StatusPaid, models.StatusAssembling, true},
+		{models.StatusAssembling, models.StatusShipped, true},
+		{models.StatusShipped, models.StatusDelivered, true},
+		{models.StatusDelivered, models.StatusReturned, true},
+		{models.StatusCreated, models.StatusCancelled, true},
+		{models.StatusCreated, models.StatusShipped, false},
+		{models.StatusShipped, models.StatusCancelled, false},
+		{models.StatusCancelled, models.StatusPaid, false},
+		{models.StatusReturned, models.StatusDelivered, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			assert.Equal(t, tt.allowed, CanTransition(tt.from, tt.to))
+		})
+	}
+}
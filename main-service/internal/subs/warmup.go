@@ -0,0 +1,104 @@
+package subs
+
+import (
+	"context"
+	"orders/internal/metrics"
+	"orders/pkg/models"
+	"time"
+)
+
+// defaultWarmUpPageSize — число заказов, читаемых из БД за один запрос при прогреве
+const defaultWarmUpPageSize = 500
+
+// Состояния прогрева кэша
+const (
+	WarmUpWarming = "warming"
+	WarmUpReady   = "ready"
+	// WarmUpFailed — прогрев прерван ошибкой; сервис работает, читая заказы из БД
+	WarmUpFailed = "failed"
+)
+
+// WarmUpOptions ограничивает набор заказов, загружаемых в кэш при запуске
+type WarmUpOptions struct {
+	// Limit — максимальное число самых новых заказов; 0 — пока в кэше есть место
+	Limit int
+	// Window — загружаются только заказы, созданные за это время; 0 — без ограничения
+	Window time.Duration
+	// PageSize — число заказов, читаемых из БД за один запрос
+	PageSize int
+}
+
+// WarmUpProgress описывает ход прогрева кэша
+type WarmUpProgress struct {
+	Status string `json:"status"`
+	Loaded int    `json:"loaded"`
+	Error  string `json:"error,omitempty"`
+}
+
+// WarmUpProgress возвращает текущий ход прогрева кэша
+func (s *Service) WarmUpProgress() WarmUpProgress {
+	return *s.warmUp.Load()
+}
+
+// WarmUpCache загружает в кэш самые новые заказы постранично, не держа в памяти их полный список.
+// Загрузка останавливается по достижении opts.Limit, границы opts.Window или заполнении кэша.
+func (s *Service) WarmUpCache(ctx context.Context, opts WarmUpOptions) error {
+	start := time.Now()
+	metrics.CacheWarmUpInProgress.Set(1)
+	defer metrics.CacheWarmUpInProgress.Set(0)
+
+	loaded, err := s.warmUpPages(ctx, opts)
+	metrics.CacheWarmUpDurationSeconds.Set(time.Since(start).Seconds())
+	if err != nil {
+		metrics.CacheWarmUpFailuresTotal.Inc()
+		s.warmUp.Store(&WarmUpProgress{Status: WarmUpFailed, Loaded: loaded, Error: err.Error()})
+		s.logger.Warnf("Service.WarmUpCache: stopped after %d orders: %v", loaded, err)
+		return err
+	}
+
+	s.warmUp.Store(&WarmUpProgress{Status: WarmUpReady, Loaded: loaded})
+	s.logger.Infof("Service.WarmUpCache: %d orders loaded in %s", loaded, time.Since(start))
+	return nil
+}
+
+func (s *Service) warmUpPages(ctx context.Context, opts WarmUpOptions) (int, error) {
+	pageSize := opts.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultWarmUpPageSize
+	}
+	var filter models.OrderFilter
+	if opts.Window > 0 {
+		filter.DateFrom = time.Now().Add(-opts.Window)
+	}
+
+	loaded := 0
+	for opts.Limit <= 0 || loaded < opts.Limit {
+		filter.Limit = pageSize
+		if opts.Limit > 0 {
+			filter.Limit = min(pageSize, opts.Limit-loaded)
+		}
+		orders, err := s.repo.ListOrders(ctx, filter)
+		if err != nil {
+			return loaded, err
+		}
+		if len(orders) == 0 {
+			break
+		}
+
+		n, err := s.cache.WarmUpCache(orders)
+		loaded += n
+		metrics.CacheWarmUpLoadedOrders.Set(float64(loaded))
+		s.warmUp.Store(&WarmUpProgress{Status: WarmUpWarming, Loaded: loaded})
+		if err != nil {
+			return loaded, err
+		}
+		if n < len(orders) {
+			s.logger.Infof("Service.WarmUpCache: cache is full after %d orders", loaded)
+			break
+		}
+
+		last := orders[len(orders)-1]
+		filter.Cursor = &models.Cursor{DateCreated: last.DateCreated, OrderUID: last.OrderUID}
+	}
+	return loaded, nil
+}
@@ -0,0 +1,157 @@
+package subs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orders/internal/metrics"
+	"orders/pkg/models"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// writeBehindQueueSize — число заказов, ожидающих фоновой записи; при заполненной
+	// очереди Create ждёт места в ней
+	writeBehindQueueSize = 1024
+	// writeBehindBatchSize — максимальное число заказов, записываемых в БД одной пачкой
+	writeBehindBatchSize = 100
+	// writeBehindAttempts — число попыток записи пачки при временной ошибке БД
+	writeBehindAttempts = 3
+	// writeBehindRetryInterval — пауза между попытками записи пачки
+	writeBehindRetryInterval = time.Second
+)
+
+// WriteFailureHandler получает заказ, который не удалось сохранить в фоне при политике
+// write-behind. Вызывающий Create к этому моменту уже получил успешный ответ,
+// поэтому об ошибке нужно сообщить отдельно, например отправить заказ в DLQ.
+type WriteFailureHandler func(order *models.OrderJSON, err error)
+
+// SetWriteFailureHandler задаёт обработчик заказов, которые не удалось сохранить в фоне
+func (s *Service) SetWriteFailureHandler(handler WriteFailureHandler) {
+	s.onWriteFailure = handler
+}
+
+// createBehind кладёт заказ в кэш и ставит его в очередь фоновой записи (политика write-behind).
+// Новый заказ получает статус created; статус из БД попадёт в кэш после записи.
+func (s *Service) createBehind(ctx context.Context, orderJSON *models.OrderJSON) error {
+	orderJSON.Status = models.StatusCreated
+	// Фоновая запись получает свою копию: repo проставляет в неё статус из БД,
+	// пока вызывающий ещё может читать orderJSON
+	pending := *orderJSON
+	s.cacheOrder(&pending)
+
+	s.pendingWrites.Add(1)
+	metrics.OrderWriteBehindPending.Inc()
+	select {
+	case s.writes <- &pending:
+		return nil
+	case <-ctx.Done():
+		s.pendingWrites.Done()
+		metrics.OrderWriteBehindPending.Dec()
+		s.invalidateOrder(&pending)
+		return ctx.Err()
+	}
+}
+
+// RunWriteBehind записывает в БД заказы, принятые по политике write-behind, пока ctx не отменён.
+// Заказы из очереди записываются пачками до writeBehindBatchSize в порядке поступления.
+func (s *Service) RunWriteBehind(ctx context.Context) {
+	if s.writes == nil {
+		return
+	}
+	s.logger.Info("Service.RunWriteBehind: writing orders in background")
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case order := <-s.writes:
+			batch := []*models.OrderJSON{order}
+		collect:
+			for len(batch) < writeBehindBatchSize {
+				select {
+				case order := <-s.writes:
+					batch = append(batch, order)
+				default:
+					break collect
+				}
+			}
+			s.writeBatch(ctx, batch)
+		}
+	}
+}
+
+// writeBatch сохраняет пачку заказов и при временной ошибке повторяет запись.
+// Сохранённые заказы обновляются в кэше статусом из БД и рассылаются подписчикам,
+// несохранённые удаляются из кэша и передаются обработчику ошибок записи.
+func (s *Service) writeBatch(ctx context.Context, orders []*models.OrderJSON) {
+	defer func() {
+		s.pendingWrites.Add(-len(orders))
+		metrics.OrderWriteBehindPending.Sub(float64(len(orders)))
+	}()
+	timer := prometheus.NewTimer(metrics.OrderProcessingDuration.WithLabelValues("write_behind"))
+	defer timer.ObserveDuration()
+
+	// Запись не прерывается отменой ctx: заказы уже подтверждены вызывающим
+	writeCtx := context.WithoutCancel(ctx)
+	var (
+		written []bool
+		results []error
+		err     error
+	)
+	for attempt := 1; ; attempt++ {
+		written, results, err = s.repo.CreateBatch(writeCtx, orders)
+		if err == nil || attempt == writeBehindAttempts || !errors.Is(err, ErrTransient) {
+			break
+		}
+		s.logger.Warnf("Service.writeBatch: attempt %d to write %d orders failed: %v", attempt, len(orders), err)
+		time.Sleep(writeBehindRetryInterval)
+	}
+	if err != nil {
+		results = make([]error, len(orders))
+		for i := range results {
+			results[i] = err
+		}
+	}
+
+	for i, order := range orders {
+		if results[i] != nil {
+			s.failWrite(order, results[i])
+			continue
+		}
+		metrics.OrdersCreatedTotal.WithLabelValues("success").Inc()
+		s.updateCache(order)
+		if written[i] {
+			s.newOrders.publish(order)
+		}
+	}
+}
+
+// failWrite удаляет из кэша заказ, который не удалось сохранить, и сообщает об ошибке
+func (s *Service) failWrite(order *models.OrderJSON, err error) {
+	metrics.OrdersCreatedTotal.WithLabelValues("error").Inc()
+	s.invalidateOrder(order)
+	s.logger.Errorf("Service.writeBatch: failed to write order %s: %v", order.OrderUID, err)
+	if s.onWriteFailure != nil {
+		s.onWriteFailure(order, err)
+	}
+}
+
+// Close дожидается записи заказов, принятых по политике write-behind
+func (s *Service) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.pendingWrites.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("Service.Close: orders are still waiting to be written: %w", ctx.Err())
+	}
+}
+
+// Name возвращает имя ресурса для логирования
+func (s *Service) Name() string { return "order service" }
@@ -3,89 +3,225 @@ package messaging
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"orders/internal/metrics"
 	"orders/internal/subs"
+	"orders/pkg/contract"
 	"orders/pkg/models"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 )
 
+// errConsumerStopped — обработка прервана остановкой потребителя
+var errConsumerStopped = errors.New("consumer stopped")
+
+const (
+	// commitTimeout ограничивает время одного подтверждения смещений
+	commitTimeout = 10 * time.Second
+	// dlqRetryInterval — пауза между попытками отправить сообщение в DLQ
+	dlqRetryInterval = 2 * time.Second
+)
+
+// ConsumerConfig содержит параметры KafkaConsumer
+type ConsumerConfig struct {
+	Brokers []string
+	// Topics — читаемые топики; обработчик сообщения выбирается Router
+	Topics  []string
+	GroupID string
+	// MaxRetries — число попыток обработки внутри потребителя, включая первую
+	MaxRetries int
+	// Backoff — паузы между попытками
+	Backoff Backoff
+	// RetryTiers — retry-топики по возрастанию задержки. Сообщение, не обработанное
+	// за MaxRetries попыток из-за временной ошибки, переходит в следующий из них,
+	// после последнего — в DLQTopic.
+	RetryTiers []RetryTier
+	// retryTier — номер читаемого retry-топика, начиная с 1; 0 — основные топики
+	retryTier int
+	// Router выбирает обработчик по топику и типу сообщения;
+	// nil — NewOrdersRouter для subs.Handler потребителя
+	Router *Router
+	// DLQTopic — топик для сообщений, которые невозможно обработать
+	DLQTopic string
+	// Workers — число параллельных обработчиков. Сообщения с одинаковым ключом
+	// (order_uid) всегда попадают в один обработчик, поэтому их порядок сохраняется.
+	Workers int
+	// WorkerQueueSize — размер очереди каждого обработчика
+	WorkerQueueSize int
+	// BatchSize — максимальное число заказов, сохраняемых обработчиком одной транзакцией
+	BatchSize int
+	// BatchTimeout — сколько обработчик ждёт заполнения пачки после первого сообщения
+	BatchTimeout time.Duration
+	// StatsInterval — период чтения статистики kafka.Reader для метрик перебалансировок
+	StatsInterval time.Duration
+}
+
 // KafkaConsumer реализует Consumer для чтения сообщений из Kafka
 type KafkaConsumer struct {
 	reader     *kafka.Reader
 	logger     *logrus.Logger
 	handler    *subs.Handler
+	dlq        Producer
+	dlqTopic   string
 	name       string
 	maxRetries int
+	backoff    Backoff
+	retryTiers []RetryTier
+	retryTier  int
+	router     *Router
+	// contracts выбирает декодер заказа по заголовкам версии и кодировки сообщения
+	contracts *contract.Registry
+
+	batchSize    int
+	batchTimeout time.Duration
+
+	workers   []chan kafka.Message
+	workersWG sync.WaitGroup
+	tracker   *offsetTracker
+	commits   chan offsetCommit
+
+	partitions    *partitionStats
+	statsInterval time.Duration
+	// watermarks запрашивает high watermark назначенных партиций
+	watermarks watermarkSource
+
+	started atomic.Bool
+	stop    chan struct{}
+	done    chan struct{}
 }
 
 // NewKafkaConsumer создает новый экземпляр KafkaConsumer
-func NewKafkaConsumer(brokers []string, topic string, groupID string, logger *logrus.Logger, handler *subs.Handler, maxRetries int) Consumer {
+func NewKafkaConsumer(cfg ConsumerConfig, logger *logrus.Logger, handler *subs.Handler, dlq Producer) Consumer {
 	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:        brokers,
-		Topic:          topic,
-		GroupID:        groupID,
+		Brokers:        cfg.Brokers,
+		GroupTopics:    cfg.Topics,
+		GroupID:        cfg.GroupID,
 		MinBytes:       10,
 		MaxBytes:       10e6,
 		CommitInterval: 0,
 	})
 
+	router := cfg.Router
+	if router == nil {
+		router = NewOrdersRouter(handler)
+	}
+
+	workers := make([]chan kafka.Message, max(cfg.Workers, 1))
+	for i := range workers {
+		workers[i] = make(chan kafka.Message, max(cfg.WorkerQueueSize, 1))
+	}
+	statsInterval := cfg.StatsInterval
+	if statsInterval <= 0 {
+		statsInterval = defaultStatsInterval
+	}
+
 	return &KafkaConsumer{
 		reader:     reader,
 		logger:     logger,
 		handler:    handler,
-		name:       "kafka consumer",
-		maxRetries: maxRetries,
+		dlq:        dlq,
+		dlqTopic:   cfg.DLQTopic,
+		name:       consumerName(cfg),
+		maxRetries: max(cfg.MaxRetries, 1),
+		backoff:    cfg.Backoff.withDefaults(),
+		retryTiers: cfg.RetryTiers,
+		retryTier:  cfg.retryTier,
+		router:     router,
+		contracts:  contract.NewRegistry(),
+
+		batchSize:    max(cfg.BatchSize, 1),
+		batchTimeout: cfg.BatchTimeout,
+
+		workers: workers,
+		tracker: newOffsetTracker(),
+		commits: make(chan offsetCommit, len(workers)),
+
+		partitions:    newPartitionStats(cfg.GroupID),
+		statsInterval: statsInterval,
+		watermarks:    &kafka.Client{Addr: kafka.TCP(cfg.Brokers...)},
+
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
 	}
 }
 
-// Run запускает потребителя Kafka
+// consumerName возвращает имя потребителя для логов остановки
+func consumerName(cfg ConsumerConfig) string {
+	if cfg.retryTier > 0 {
+		return "kafka retry consumer " + cfg.Topics[0]
+	}
+	return "kafka consumer"
+}
+
+// Run запускает потребителя Kafka: цикл чтения раскладывает сообщения по обработчикам,
+// а подтверждения смещений выполняются отдельной горутиной
 func (c *KafkaConsumer) Run(ctx context.Context) {
+	c.started.Store(true)
+	defer close(c.done)
+
 	c.logger.Info("KafkaConsumer.Run: Starting consumer...")
-	c.logger.Infof("KafkaConsumer.Run: Brokers: %v, Topic: %s, GroupID: %s",
+	c.logger.Infof("KafkaConsumer.Run: Brokers: %v, Topics: %v, GroupID: %s, Workers: %d",
 		c.reader.Config().Brokers,
-		c.reader.Config().Topic,
-		c.reader.Config().GroupID)
+		c.reader.Config().GroupTopics,
+		c.reader.Config().GroupID,
+		len(c.workers))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	committerDone := make(chan struct{})
+	go func() {
+		defer close(committerDone)
+		c.runCommitter()
+	}()
+
+	for i, queue := range c.workers {
+		c.workersWG.Add(1)
+		go c.runWorker(ctx, i, queue)
+	}
+	go c.collectStats(ctx)
 
 	for {
 		select {
 		case <-ctx.Done():
 			c.logger.Info("KafkaConsume.Run: Consumer stop (context canceled)")
+			c.workersWG.Wait()
+			close(c.commits)
+			<-committerDone
+			c.partitions.RevokeAll()
 			return
 		default:
 			if err := c.ConsumeMessage(ctx); err != nil {
 				if errors.Is(err, context.Canceled) {
-					return
+					continue
 				}
 				c.logger.Errorf("KafkaConsumer: Error consuming message: %v", err)
-				time.Sleep(2 * time.Second)
+				sleepCtx(ctx, 2*time.Second)
 			}
 		}
 	}
-
 }
 
-// ConsumeMessage читает и обрабатывает сообщения из Kafka
+// ConsumeMessage читает одно сообщение из Kafka и передаёт его обработчику,
+// выбранному по ключу сообщения
 func (c *KafkaConsumer) ConsumeMessage(ctx context.Context) error {
-	startTime := time.Now()
-	var timeMetricStatus string
-	topic := c.reader.Config().Topic
-
-	defer func() {
-		processingDuration := time.Since(startTime).Seconds()
-		metrics.KafkaMessageProcessingDuration.WithLabelValues(topic, timeMetricStatus).Observe(processingDuration)
-	}()
-	timeMetricStatus = "error"
+	topic := strings.Join(c.reader.Config().GroupTopics, ",")
 
-	metrics.KafkaMessagesTotal.WithLabelValues(topic, "received", "none").Inc()
-
-	kafkaMsg, err := c.reader.ReadMessage(ctx)
+	kafkaMsg, err := c.reader.FetchMessage(ctx)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			metrics.KafkaMessagesTotal.WithLabelValues(topic, "error", "context_canceled").Inc()
@@ -95,79 +231,424 @@ func (c *KafkaConsumer) ConsumeMessage(ctx context.Context) error {
 		c.logger.Errorf("KafkaConsumer.ConsumeMessage: failed to fetch msg: %v", err)
 		return fmt.Errorf("fetch message: %w", err)
 	}
-	log := c.logger.WithFields(logrus.Fields{
-		"topic":     topic,
-		"partition": kafkaMsg.Partition,
-		"offset":    kafkaMsg.Offset,
-		"key":       kafkaMsg.Key,
-	})
-	log.Info("KafkaConsumer.Run: Received kafka message")
-	var order *models.OrderJSON
-	if err := json.Unmarshal(kafkaMsg.Value, &order); err != nil {
-		metrics.KafkaMessagesTotal.WithLabelValues(topic, "error", "parse").Inc()
-		c.handlePermanentErr(ctx, log, kafkaMsg, "json_unmarshal", err)
-		return nil
+	c.partitions.Fetched(kafkaMsg)
+	// Сообщение из retry-топика не подтверждено и при остановке будет прочитано заново
+	if !c.waitRetryTime(ctx, kafkaMsg) {
+		return ctx.Err()
 	}
-	validate := validator.New()
-	if err := validate.Struct(order); err != nil {
-		metrics.KafkaMessagesTotal.WithLabelValues(topic, "error", "validation").Inc()
-		c.handlePermanentErr(ctx, log, kafkaMsg, "validation", err)
+	metrics.KafkaMessagesTotal.WithLabelValues(kafkaMsg.Topic, "received", "none").Inc()
+
+	c.tracker.Add(kafkaMsg)
+	metrics.KafkaInFlightMessages.WithLabelValues(kafkaMsg.Topic).Set(float64(c.tracker.Pending()))
+
+	select {
+	case c.workers[c.workerIndex(kafkaMsg)] <- kafkaMsg:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *KafkaConsumer) workerIndex(msg kafka.Message) int {
+	if len(msg.Key) == 0 {
+		return msg.Partition % len(c.workers)
 	}
+	h := fnv.New32a()
+	_, _ = h.Write(msg.Key)
+	return int(h.Sum32() % uint32(len(c.workers)))
+}
 
-	log = log.WithField("order_uid", order.OrderUID)
-	var commitErr error
-	processingSuccess := false
-	attempts := 0
+// runWorker накапливает сообщения в пачку и сохраняет её, когда пачка заполнена
+// или с момента получения первого сообщения прошло batchTimeout
+func (c *KafkaConsumer) runWorker(ctx context.Context, id int, queue <-chan kafka.Message) {
+	defer c.workersWG.Done()
+	c.logger.Debugf("KafkaConsumer.runWorker: worker %d started", id)
 
-	for attempt := 1; attempt <= c.maxRetries; attempt++ {
-		log.WithField("attempt", attempt).Info("Processing order")
-		attempts = attempt
-		if err = c.handler.Create(ctx, order); err == nil {
-			processingSuccess = true
-			break
+	batch := make([]kafka.Message, 0, c.batchSize)
+	timer := time.NewTimer(c.batchTimeout)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Ненаписанная пачка не подтверждается и будет прочитана повторно
+			return
+		case msg := <-queue:
+			batch = append(batch, msg)
+			if len(batch) < c.batchSize {
+				if len(batch) == 1 {
+					timer.Reset(c.batchTimeout)
+				}
+				continue
+			}
+			timer.Stop()
+		case <-timer.C:
 		}
 
-		if c.isTemporaryError(err) && attempt < c.maxRetries {
-			backoff := time.Duration(attempt) * time.Second
-			log.WithField("backoff_seconds", attempt).Warnf("Temporary error, retrying %v", err)
-			time.Sleep(backoff)
+		if !c.processBatch(ctx, batch) {
+			// Обработка прервана остановкой: оставшиеся сообщения не подтверждаются
+			return
+		}
+		batch = batch[:0]
+	}
+}
+
+// markDone отмечает сообщение обработанным и передаёт на подтверждение
+// сдвинувшуюся границу непрерывно обработанных сообщений партиции
+func (c *KafkaConsumer) markDone(msg kafka.Message) {
+	if commitMsg, ok := c.tracker.Done(msg); ok {
+		c.commits <- commitMsg
+	}
+	metrics.KafkaInFlightMessages.WithLabelValues(msg.Topic).Set(float64(c.tracker.Pending()))
+}
+
+// runCommitter подтверждает смещения, не допуская их отката внутри партиции.
+// Накопившиеся подтверждения отправляются одним запросом с наибольшим смещением каждой партиции.
+// Перед отправкой проверяется перебалансировка: смещения, обработанные до неё, отбрасываются.
+func (c *KafkaConsumer) runCommitter() {
+	committed := make(map[partitionKey]int64)
+	committedEpoch := c.tracker.Epoch()
+	for msg := range c.commits {
+		latest := map[partitionKey]offsetCommit{partitionOf(msg.Message): msg}
+	drain:
+		for {
+			select {
+			case next, ok := <-c.commits:
+				if !ok {
+					break drain
+				}
+				if cur, ok := latest[partitionOf(next.Message)]; !ok || next.epoch > cur.epoch ||
+					(next.epoch == cur.epoch && next.Offset > cur.Offset) {
+					latest[partitionOf(next.Message)] = next
+				}
+			default:
+				break drain
+			}
+		}
+
+		c.checkRebalance()
+		epoch := c.tracker.Epoch()
+		if epoch != committedEpoch {
+			// После перебалансировки партиции заново назначены, и их смещения начинаются
+			// с подтверждённых в группе, а не с подтверждённых этим потребителем
+			committed = make(map[partitionKey]int64)
+			committedEpoch = epoch
+		}
+		msgs := make([]kafka.Message, 0, len(latest))
+		revoked := 0
+		for key, m := range latest {
+			if m.epoch != epoch {
+				revoked++
+				continue
+			}
+			if last, ok := committed[key]; ok && m.Offset <= last {
+				continue
+			}
+			msgs = append(msgs, m.Message)
+		}
+		if revoked > 0 {
+			c.logger.Infof("KafkaConsumer.runCommitter: dropped offsets of %d revoked partitions", revoked)
+		}
+		if len(msgs) == 0 {
 			continue
 		}
 
-		break
+		ctx, cancel := context.WithTimeout(context.Background(), commitTimeout)
+		err := c.Commit(ctx, msgs...)
+		cancel()
+		if err != nil {
+			metrics.KafkaMessagesTotal.WithLabelValues(msg.Topic, "error", "commit").Add(float64(len(msgs)))
+			c.logger.Errorf("KafkaConsumer.runCommitter: failed to commit offsets for %d partitions: %v", len(msgs), err)
+			continue
+		}
+		for _, m := range msgs {
+			committed[partitionOf(m)] = m.Offset
+			c.partitions.Committed(m)
+		}
 	}
-	finalStatus := "error"
-	if processingSuccess {
-		finalStatus = "success"
+}
+
+// processBatch обрабатывает пачку сообщений в порядке получения. Заказы накапливаются и
+// сохраняются одной транзакцией; перед сообщением другого типа накопленные заказы сохраняются,
+// чтобы, например, смена статуса не обогнала создание заказа. Возвращает false, если обработка
+// была прервана остановкой потребителя.
+func (c *KafkaConsumer) processBatch(ctx context.Context, batch []kafka.Message) bool {
+	startTime := time.Now()
+	metrics.KafkaBatchSize.WithLabelValues(batch[0].Topic).Observe(float64(len(batch)))
+
+	msgs := make([]kafka.Message, 0, len(batch))
+	orders := make([]*models.OrderJSON, 0, len(batch))
+	for _, kafkaMsg := range batch {
+		log := c.messageLogger(kafkaMsg)
+		log.Info("KafkaConsumer.processBatch: Received kafka message")
+
+		headers := fromKafkaHeaders(kafkaMsg.Headers)
+		rt := c.router.route(c.sourceTopic(kafkaMsg, headers), headers)
+		if !rt.orders {
+			if !c.saveOrders(ctx, msgs, orders, startTime) {
+				return false
+			}
+			msgs, orders = msgs[:0], orders[:0]
+			if !c.processMessage(ctx, kafkaMsg, rt.handler, startTime) {
+				return false
+			}
+			c.markDone(kafkaMsg)
+			continue
+		}
+
+		order, errType, err := c.decodeOrder(kafkaMsg)
+		if err != nil {
+			if !c.rejectMessage(ctx, log, kafkaMsg, errType, err, startTime) {
+				return false
+			}
+			c.markDone(kafkaMsg)
+			continue
+		}
+		msgs = append(msgs, kafkaMsg)
+		orders = append(orders, order)
+	}
+	return c.saveOrders(ctx, msgs, orders, startTime)
+}
+
+// saveOrders сохраняет заказы одной транзакцией и подтверждает их сообщения.
+// Заказы, которые не удалось сохранить в составе пачки, обрабатываются по одному.
+func (c *KafkaConsumer) saveOrders(ctx context.Context, msgs []kafka.Message, orders []*models.OrderJSON, startTime time.Time) bool {
+	if len(orders) == 0 {
+		return true
+	}
+
+	// Начатая запись в БД завершается даже при остановке потребителя
+	results, batchErr := c.handler.CreateBatch(context.WithoutCancel(ctx), orders)
+	if batchErr != nil {
+		c.logger.Warnf("KafkaConsumer.saveOrders: failed to save batch of %d orders: %v", len(orders), batchErr)
 	}
-	metrics.KafkaProcessingAttempts.WithLabelValues(topic, finalStatus).Observe(float64(attempts))
 
-	if !processingSuccess {
+	for i, kafkaMsg := range msgs {
+		err := batchErr
+		if err == nil {
+			err = results[i]
+		}
+		if !c.processOrder(ctx, kafkaMsg, orders[i], err, startTime) {
+			return false
+		}
+		c.markDone(kafkaMsg)
+	}
+	return true
+}
+
+// rejectMessage учитывает сообщение, которое невозможно обработать, и отправляет его в DLQ
+func (c *KafkaConsumer) rejectMessage(ctx context.Context, log *logrus.Entry, kafkaMsg kafka.Message, errType string, err error, startTime time.Time) bool {
+	metricErrType := errType
+	if errType == "json_unmarshal" || errType == "decode" {
+		metricErrType = "parse"
+	}
+	metrics.KafkaMessagesTotal.WithLabelValues(kafkaMsg.Topic, "error", metricErrType).Inc()
+	if !c.handlePermanentErr(ctx, log, kafkaMsg, errType, err) {
+		return false
+	}
+	metrics.KafkaMessageProcessingDuration.WithLabelValues(kafkaMsg.Topic, "error").Observe(time.Since(startTime).Seconds())
+	return true
+}
+
+// decodeOrder разбирает заказ декодером, выбранным по версии контракта и кодировке
+// из заголовков сообщения, и валидирует его. При ошибке возвращает её тип для метрик и DLQ;
+// сообщения неизвестной версии или кодировки отклоняются с типом unsupported_schema.
+func (c *KafkaConsumer) decodeOrder(kafkaMsg kafka.Message) (*models.OrderJSON, string, error) {
+	codec, err := c.contracts.Resolve(fromKafkaHeaders(kafkaMsg.Headers))
+	if err != nil {
+		return nil, "unsupported_schema", err
+	}
+	order, err := codec.Decode(kafkaMsg.Value)
+	if err != nil {
+		if codec.Encoding() == contract.EncodingJSON {
+			return nil, "json_unmarshal", err
+		}
+		return nil, "decode", fmt.Errorf("decode %s v%s: %w", codec.Encoding(), codec.Version(), err)
+	}
+	if err := subs.ValidateOrder(order); err != nil {
+		return nil, "validation", err
+	}
+	return order, "", nil
+}
+
+// processOrder завершает обработку заказа по результату первой попытки записи (firstErr)
+// и при временной ошибке повторяет запись по одному заказу. Возвращает false,
+// если обработка была прервана остановкой потребителя.
+func (c *KafkaConsumer) processOrder(ctx context.Context, kafkaMsg kafka.Message, order *models.OrderJSON, firstErr error, startTime time.Time) bool {
+	topic := kafkaMsg.Topic
+	log := c.messageLogger(kafkaMsg).WithField("order_uid", order.OrderUID)
+	// Начатая запись в БД завершается даже при остановке потребителя
+	writeCtx := context.WithoutCancel(ctx)
+
+	attempts, err := c.retry(ctx, log, firstErr, func() error {
+		return c.handler.Create(writeCtx, order)
+	})
+	if errors.Is(err, errConsumerStopped) {
+		return false
+	}
+	c.observeAttempts(topic, attempts, err, startTime)
+
+	switch {
+	case err == nil:
+		metrics.KafkaMessagesTotal.WithLabelValues(topic, "success", "none").Inc()
+		log.Info("Order successfully processed")
+	case errors.Is(err, subs.ErrDuplicate):
+		// Заказ с таким UID уже сохранён и отклонён политикой дубликатов
+		metrics.KafkaMessagesTotal.WithLabelValues(topic, "error", "duplicate").Inc()
+		log.Warnf("Order rejected as duplicate: %v", err)
+	case errors.Is(err, subs.ErrValidation):
+		metrics.KafkaMessagesTotal.WithLabelValues(topic, "error", "validation").Inc()
+		return c.handlePermanentErr(ctx, log, kafkaMsg, "validation", err)
+	case isTemporaryError(err):
+		return c.deferRetry(ctx, log, kafkaMsg, err)
+	default:
+		// Неклассифицированная ошибка не подтверждается молча: сообщение уходит в DLQ
 		metrics.KafkaMessagesTotal.WithLabelValues(topic, "error", "processing").Inc()
-	} else {
-        if commitErr = c.Commit(ctx, kafkaMsg); commitErr != nil {
-            metrics.KafkaMessagesTotal.WithLabelValues(topic, "error", "commit").Inc()
-            log.Errorf("Failed to commit after error %v", commitErr)
-        } else {
-            metrics.KafkaMessagesTotal.WithLabelValues(topic, "success", "none").Inc()
-            timeMetricStatus = "success"
-            log.WithField("order_uid", order.OrderUID).Info("Order successfully processed and committed")
-        }
-    }
+		log.Errorf("Failed to process order: %v", err)
+		return c.handlePermanentErr(ctx, log, kafkaMsg, "processing", err)
+	}
+	return true
+}
+
+// processMessage обрабатывает сообщение обработчиком из Router и повторяет обработку
+// при временной ошибке. Сообщение с PermanentError отправляется в DLQ с её типом, с другой
+// невременной ошибкой — с типом processing. Возвращает false,
+// если обработка была прервана остановкой потребителя.
+func (c *KafkaConsumer) processMessage(ctx context.Context, kafkaMsg kafka.Message, handler Handler, startTime time.Time) bool {
+	topic := kafkaMsg.Topic
+	msg := Message{Key: kafkaMsg.Key, Value: kafkaMsg.Value, Headers: fromKafkaHeaders(kafkaMsg.Headers)}
+	log := c.messageLogger(kafkaMsg).WithField("event_type", msg.Headers[HeaderEventType])
+
+	// Начатая обработка завершается даже при остановке потребителя
+	handleCtx := context.WithoutCancel(ctx)
+	handle := func() error { return handler(handleCtx, msg) }
+
+	attempts, err := c.retry(ctx, log, handle(), handle)
+	if errors.Is(err, errConsumerStopped) {
+		return false
+	}
 
-	return nil
+	var permanent *PermanentError
+	switch {
+	case errors.As(err, &permanent):
+		return c.rejectMessage(ctx, log, kafkaMsg, permanent.Type, permanent.Err, startTime)
+	case isTemporaryError(err):
+		c.observeAttempts(topic, attempts, err, startTime)
+		return c.deferRetry(ctx, log, kafkaMsg, err)
+	case err != nil:
+		// Неклассифицированная ошибка обработчика не подтверждается молча: сообщение уходит в DLQ
+		log.Errorf("Failed to process message: %v", err)
+		return c.rejectMessage(ctx, log, kafkaMsg, "processing", err, startTime)
+	}
+	c.observeAttempts(topic, attempts, err, startTime)
+	metrics.KafkaMessagesTotal.WithLabelValues(topic, "success", "none").Inc()
+	log.Info("Message processed")
+	return true
+}
+
+// retry повторяет op, пока она завершается временной ошибкой, не исчерпаны попытки
+// и не истекло Backoff.MaxElapsed. err — результат первой попытки. Возвращает число попыток
+// и итоговую ошибку; при остановке потребителя во время ожидания возвращается errConsumerStopped.
+func (c *KafkaConsumer) retry(ctx context.Context, log *logrus.Entry, err error, op func() error) (int, error) {
+	attempts := 1
+	start := time.Now()
+	for isTemporaryError(err) && attempts < c.maxRetries {
+		backoff := c.backoff.Delay(attempts)
+		if c.backoff.MaxElapsed > 0 && time.Since(start)+backoff > c.backoff.MaxElapsed {
+			break
+		}
+		log.WithField("backoff", backoff.String()).Warnf("Temporary error, retrying %v", err)
+		if !sleepCtx(ctx, backoff) {
+			return attempts, errConsumerStopped
+		}
+		attempts++
+		log.WithField("attempt", attempts).Info("Processing message")
+		err = op()
+	}
+	return attempts, err
+}
+
+func (c *KafkaConsumer) observeAttempts(topic string, attempts int, err error, startTime time.Time) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	metrics.KafkaProcessingAttempts.WithLabelValues(topic, status).Observe(float64(attempts))
+	metrics.KafkaMessageProcessingDuration.WithLabelValues(topic, status).Observe(time.Since(startTime).Seconds())
+}
+
+func (c *KafkaConsumer) messageLogger(kafkaMsg kafka.Message) *logrus.Entry {
+	return c.logger.WithFields(logrus.Fields{
+		"topic":     kafkaMsg.Topic,
+		"partition": kafkaMsg.Partition,
+		"offset":    kafkaMsg.Offset,
+		"key":       string(kafkaMsg.Key),
+	})
 }
 
-// Commit подтверждает обработку сообщения в Kafka
-func (c *KafkaConsumer) Commit(ctx context.Context, msg kafka.Message) error {
-	return c.reader.CommitMessages(ctx, msg)
+// Commit подтверждает обработку сообщений в Kafka
+func (c *KafkaConsumer) Commit(ctx context.Context, msgs ...kafka.Message) error {
+	return c.reader.CommitMessages(ctx, msgs...)
 }
 
-// Close закрывает соединение с Kafka
+// Close останавливает чтение, дожидается завершения обработчиков и подтверждения
+// смещений, после чего закрывает соединение с Kafka
 func (c *KafkaConsumer) Close(ctx context.Context) error {
 	c.logger.Info("KafkaConsumer.Close: Closing Kafka consumer")
+	close(c.stop)
+	if c.started.Load() {
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			c.logger.Warn("KafkaConsumer.Close: workers did not stop in time")
+		}
+	}
 	return c.reader.Close()
 }
 
 func (c *KafkaConsumer) Name() string { return c.name }
+
+// Ping проверяет, что хотя бы один брокер из конфигурации принимает соединения
+func (c *KafkaConsumer) Ping(ctx context.Context) error {
+	var errs []error
+	for _, broker := range c.reader.Config().Brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("no kafka broker available: %w", errors.Join(errs...))
+}
+
+// Lag возвращает суммарное отставание подтверждённых смещений от high watermark
+// по назначенным партициям
+func (c *KafkaConsumer) Lag() int64 {
+	return c.partitions.Lag()
+}
+
+// Running сообщает, что цикл чтения запущен и ещё не остановлен
+func (c *KafkaConsumer) Running() bool {
+	if !c.started.Load() {
+		return false
+	}
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// sleepCtx ждёт d или отмены ctx и возвращает false, если ожидание было прервано
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"orders/pkg/contract"
+	"orders/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOrder() *models.OrderJSON {
+	return &models.OrderJSON{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Entry:       "WBIL",
+		Locale:      "en",
+		DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC),
+		Delivery: models.Delivery{
+			OrderUID: "b563feb7b2b84b6test", Name: "Test Testov", Phone: "+9720000000", Zip: "2639809",
+			City: "Kiryat Mozkin", Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction: "b563feb7b2b84b6test", Currency: "USD", Provider: "wbpay",
+			Amount: 1817, PaymentDT: 1637907727, Bank: "alpha",
+		},
+		Items: []models.Item{{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453,
+			RID: "ab4219087a764ae0btest", Name: "Mascaras", TotalPrice: 317, NmID: 2389212}},
+	}
+}
+
+// encodeMessage кодирует заказ в сообщение Kafka с заголовками контракта
+func encodeMessage(t *testing.T, encoding contract.Encoding) kafka.Message {
+	t.Helper()
+	codec, err := contract.NewRegistry().Codec(contract.CurrentVersion, encoding)
+	require.NoError(t, err)
+	value, err := codec.Encode(testOrder())
+	require.NoError(t, err)
+	return kafka.Message{Value: value, Headers: toKafkaHeaders(contract.Headers(codec))}
+}
+
+// TestDecodeOrder_PerMessageEncoding тестирует выбор декодера по заголовкам каждого сообщения
+func TestDecodeOrder_PerMessageEncoding(t *testing.T) {
+	c := &KafkaConsumer{contracts: contract.NewRegistry()}
+	for _, encoding := range []contract.Encoding{contract.EncodingJSON, contract.EncodingProtobuf, contract.EncodingAvro} {
+		order, errType, err := c.decodeOrder(encodeMessage(t, encoding))
+
+		require.NoError(t, err, encoding)
+		assert.Empty(t, errType)
+		assert.Equal(t, testOrder(), order)
+	}
+}
+
+// TestDecodeOrder_UnsupportedSchema тестирует отклонение сообщений неизвестной версии
+func TestDecodeOrder_UnsupportedSchema(t *testing.T) {
+	c := &KafkaConsumer{contracts: contract.NewRegistry()}
+	msg := encodeMessage(t, contract.EncodingJSON)
+	msg.Headers = toKafkaHeaders(map[string]string{contract.HeaderSchemaVersion: "99"})
+
+	_, errType, err := c.decodeOrder(msg)
+
+	assert.ErrorIs(t, err, contract.ErrUnsupportedVersion)
+	assert.Equal(t, "unsupported_schema", errType)
+}
+
+// TestDecodeOrder_MalformedAvro тестирует тип ошибки для повреждённого тела
+func TestDecodeOrder_MalformedAvro(t *testing.T) {
+	c := &KafkaConsumer{contracts: contract.NewRegistry()}
+	msg := encodeMessage(t, contract.EncodingAvro)
+	msg.Value = msg.Value[:10]
+
+	_, errType, err := c.decodeOrder(msg)
+
+	assert.Error(t, err)
+	assert.Equal(t, "decode", errType)
+}
+
+// TestProcessOrder_UnclassifiedError тестирует, что сообщение с неклассифицированной
+// ошибкой записи отправляется в DLQ, а не подтверждается
+func TestProcessOrder_UnclassifiedError(t *testing.T) {
+	dlq := &recordingProducer{}
+	c := &KafkaConsumer{logger: testLogger(), dlq: dlq, dlqTopic: "orders.dlq", maxRetries: 3}
+
+	require.True(t, c.processOrder(context.Background(), encodeMessage(t, contract.EncodingJSON), testOrder(), errors.New("unexpected"), time.Now()))
+
+	require.Len(t, dlq.sent, 1)
+	assert.Equal(t, "processing", dlq.sent[0].Headers[HeaderErrorType])
+	assert.Equal(t, "unexpected", dlq.sent[0].Headers[HeaderErrorMessage])
+}
@@ -0,0 +1,186 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orders/internal/metrics"
+	"orders/pkg/contract"
+	"orders/pkg/models"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Заголовки, которыми помечаются сообщения в dead-letter топике
+const (
+	HeaderErrorType         = "x-error-type"
+	HeaderErrorMessage      = "x-error-message"
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderOriginalTimestamp = "x-original-timestamp"
+	HeaderFailedAt          = "x-failed-at"
+	HeaderReplayCount       = "x-replay-count"
+)
+
+// dlqIdleTimeout — время ожидания нового сообщения, после которого реплей считается завершённым
+const dlqIdleTimeout = 5 * time.Second
+
+// newDLQMessage формирует сообщение для dead-letter топика с описанием ошибки
+func newDLQMessage(msg kafka.Message, errType string, err error) Message {
+	headers := fromKafkaHeaders(msg.Headers)
+	headers[HeaderErrorType] = errType
+	headers[HeaderErrorMessage] = err.Error()
+	// Сообщение из retry-топика уже помечено исходным топиком
+	if headers[HeaderOriginalTopic] == "" {
+		setOriginHeaders(headers, msg)
+	}
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	return Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
+
+// SendOrderToDLQ отправляет в dead-letter топик заказ, исходное сообщение которого уже
+// подтверждено, например если заказ не удалось записать в БД в фоне. Заказ кодируется
+// в JSON текущей версии контракта и при реплее возвращается в topic.
+func SendOrderToDLQ(ctx context.Context, producer Producer, dlqTopic, topic string, order *models.OrderJSON, errType string, cause error) error {
+	codec, err := contract.NewRegistry().Codec(contract.CurrentVersion, contract.EncodingJSON)
+	if err != nil {
+		return fmt.Errorf("SendOrderToDLQ: %w", err)
+	}
+	value, err := codec.Encode(order)
+	if err != nil {
+		return fmt.Errorf("SendOrderToDLQ: encode order %s: %w", order.OrderUID, err)
+	}
+	headers := contract.Headers(codec)
+	headers[HeaderErrorType] = errType
+	headers[HeaderErrorMessage] = cause.Error()
+	headers[HeaderOriginalTopic] = topic
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339Nano)
+
+	msg := Message{Key: []byte(order.OrderUID), Value: value, Headers: headers}
+	if err := producer.ProduceMessage(ctx, dlqTopic, msg); err != nil {
+		metrics.KafkaDeadLetterTotal.WithLabelValues(topic, errType, "failed").Inc()
+		return fmt.Errorf("SendOrderToDLQ: produce to %s: %w", dlqTopic, err)
+	}
+	metrics.KafkaDeadLetterTotal.WithLabelValues(topic, errType, "published").Inc()
+	return nil
+}
+
+// setOriginHeaders записывает в заголовки топик, партицию, смещение и время сообщения msg
+func setOriginHeaders(headers map[string]string, msg kafka.Message) {
+	headers[HeaderOriginalTopic] = msg.Topic
+	headers[HeaderOriginalPartition] = strconv.Itoa(msg.Partition)
+	headers[HeaderOriginalOffset] = strconv.FormatInt(msg.Offset, 10)
+	headers[HeaderOriginalTimestamp] = msg.Time.UTC().Format(time.RFC3339Nano)
+}
+
+// newReplayMessage восстанавливает исходное сообщение из dead-letter топика
+func newReplayMessage(msg kafka.Message) Message {
+	headers := fromKafkaHeaders(msg.Headers)
+	replayCount, _ := strconv.Atoi(headers[HeaderReplayCount])
+	for _, key := range []string{
+		HeaderErrorType,
+		HeaderErrorMessage,
+		HeaderOriginalTopic,
+		HeaderOriginalPartition,
+		HeaderOriginalOffset,
+		HeaderOriginalTimestamp,
+		HeaderFailedAt,
+		HeaderRetryCount,
+		HeaderRetryNotBefore,
+		HeaderRetryError,
+	} {
+		delete(headers, key)
+	}
+	headers[HeaderReplayCount] = strconv.Itoa(replayCount + 1)
+
+	return Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
+
+// DLQReplayer перекладывает сообщения из dead-letter топика обратно в основной
+type DLQReplayer struct {
+	reader   *kafka.Reader
+	producer Producer
+	topic    string
+	logger   *logrus.Logger
+}
+
+// NewDLQReplayer создает новый экземпляр DLQReplayer.
+// Если topic пуст, сообщение возвращается в топик из заголовка x-original-topic.
+func NewDLQReplayer(brokers []string, dlqTopic, groupID, topic string, producer Producer, logger *logrus.Logger) *DLQReplayer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        brokers,
+		Topic:          dlqTopic,
+		GroupID:        groupID,
+		MinBytes:       10,
+		MaxBytes:       10e6,
+		CommitInterval: 0,
+	})
+	return &DLQReplayer{
+		reader:   reader,
+		producer: producer,
+		topic:    topic,
+		logger:   logger,
+	}
+}
+
+// Replay переотправляет до limit сообщений (0 — без ограничения) и возвращает их количество.
+// Реплей завершается, когда в dead-letter топике нет новых сообщений в течение dlqIdleTimeout.
+func (r *DLQReplayer) Replay(ctx context.Context, limit int) (int, error) {
+	dlqTopic := r.reader.Config().Topic
+	replayed := 0
+	for limit == 0 || replayed < limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, dlqIdleTimeout)
+		msg, err := r.reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				r.logger.Infof("DLQReplayer.Replay: no more messages in %s", dlqTopic)
+				return replayed, nil
+			}
+			return replayed, fmt.Errorf("DLQReplayer.Replay: fetch message: %w", err)
+		}
+
+		target := r.topic
+		if target == "" {
+			target = fromKafkaHeaders(msg.Headers)[HeaderOriginalTopic]
+		}
+		if target == "" {
+			return replayed, fmt.Errorf("DLQReplayer.Replay: message at offset %d has no %s header", msg.Offset, HeaderOriginalTopic)
+		}
+
+		if err := r.producer.ProduceMessage(ctx, target, newReplayMessage(msg)); err != nil {
+			metrics.KafkaDLQReplayedTotal.WithLabelValues(dlqTopic, "error").Inc()
+			return replayed, fmt.Errorf("DLQReplayer.Replay: produce to %s: %w", target, err)
+		}
+		if err := r.reader.CommitMessages(ctx, msg); err != nil {
+			return replayed, fmt.Errorf("DLQReplayer.Replay: commit: %w", err)
+		}
+		metrics.KafkaDLQReplayedTotal.WithLabelValues(dlqTopic, "success").Inc()
+		replayed++
+
+		r.logger.WithFields(logrus.Fields{
+			"dlq_offset": msg.Offset,
+			"topic":      target,
+			"key":        string(msg.Key),
+		}).Info("DLQReplayer.Replay: message replayed")
+	}
+	return replayed, nil
+}
+
+// Close закрывает reader dead-letter топика
+func (r *DLQReplayer) Close() error {
+	return r.reader.Close()
+}
@@ -0,0 +1,83 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"orders/pkg/contract"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewDLQMessage тестирует заголовки сообщения, отправляемого в DLQ
+func TestNewDLQMessage(t *testing.T) {
+	msg := kafka.Message{
+		Topic:     "orders",
+		Partition: 2,
+		Offset:    42,
+		Key:       []byte("test-123"),
+		Value:     []byte(`{"order_uid":"short"}`),
+		Time:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	dlqMsg := newDLQMessage(msg, "validation", errors.New("OrderUID failed on the 'min' tag"))
+
+	assert.Equal(t, msg.Key, dlqMsg.Key)
+	assert.Equal(t, msg.Value, dlqMsg.Value)
+	assert.Equal(t, "validation", dlqMsg.Headers[HeaderErrorType])
+	assert.Equal(t, "OrderUID failed on the 'min' tag", dlqMsg.Headers[HeaderErrorMessage])
+	assert.Equal(t, "orders", dlqMsg.Headers[HeaderOriginalTopic])
+	assert.Equal(t, "2", dlqMsg.Headers[HeaderOriginalPartition])
+	assert.Equal(t, "42", dlqMsg.Headers[HeaderOriginalOffset])
+	assert.Equal(t, "2024-05-01T12:00:00Z", dlqMsg.Headers[HeaderOriginalTimestamp])
+	assert.NotEmpty(t, dlqMsg.Headers[HeaderFailedAt])
+}
+
+// TestNewReplayMessage тестирует очистку заголовков DLQ и счётчик реплеев
+func TestNewReplayMessage(t *testing.T) {
+	dlqMsg := newDLQMessage(kafka.Message{
+		Topic:   "orders",
+		Key:     []byte("test-123"),
+		Value:   []byte(`{}`),
+		Headers: []kafka.Header{{Key: "trace-id", Value: []byte("abc")}},
+	}, "json_unmarshal", errors.New("unexpected end of JSON input"))
+
+	replayed := newReplayMessage(kafka.Message{
+		Key:     dlqMsg.Key,
+		Value:   dlqMsg.Value,
+		Headers: toKafkaHeaders(dlqMsg.Headers),
+	})
+
+	assert.Equal(t, map[string]string{
+		"trace-id":        "abc",
+		HeaderReplayCount: "1",
+	}, replayed.Headers)
+}
+
+// TestSendOrderToDLQ тестирует отправку в DLQ заказа, который не удалось записать в фоне:
+// при реплее он декодируется как заказ из исходного топика
+func TestSendOrderToDLQ(t *testing.T) {
+	producer := &recordingProducer{}
+	order := testOrder()
+
+	err := SendOrderToDLQ(context.Background(), producer, "orders.dlq", "orders", order, "write_behind", errors.New("connection refused"))
+	require.NoError(t, err)
+
+	require.Len(t, producer.sent, 1)
+	assert.Equal(t, []string{"orders.dlq"}, producer.topics)
+	dlqMsg := producer.sent[0]
+	assert.Equal(t, []byte(order.OrderUID), dlqMsg.Key)
+	assert.Equal(t, "write_behind", dlqMsg.Headers[HeaderErrorType])
+	assert.Equal(t, "connection refused", dlqMsg.Headers[HeaderErrorMessage])
+	assert.Equal(t, "orders", dlqMsg.Headers[HeaderOriginalTopic])
+
+	replayed := newReplayMessage(kafka.Message{Key: dlqMsg.Key, Value: dlqMsg.Value, Headers: toKafkaHeaders(dlqMsg.Headers)})
+	codec, err := contract.NewRegistry().Resolve(replayed.Headers)
+	require.NoError(t, err)
+	decoded, err := codec.Decode(replayed.Value)
+	require.NoError(t, err)
+	assert.Equal(t, order.OrderUID, decoded.OrderUID)
+}
@@ -0,0 +1,61 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"orders/internal/subs"
+	"orders/pkg/models"
+)
+
+// HeaderEventType — заголовок с типом события; сообщение без него считается созданием заказа
+const HeaderEventType = "x-event-type"
+
+// Типы событий в топике заказов
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderStatusChanged = "order.status_changed"
+)
+
+// NewOrdersRouter создает Router для топиков заказов: сообщения без типа и order.created
+// сохраняются пакетной записью заказов, order.status_changed меняет статус через handler
+func NewOrdersRouter(handler *subs.Handler) *Router {
+	router := NewRouter()
+	router.HandleOrders("", "")
+	router.HandleOrders("", EventOrderCreated)
+	router.Handle("", EventOrderStatusChanged, NewStatusHandler(handler))
+	return router
+}
+
+// decodeStatusEvent разбирает и валидирует событие смены статуса
+func decodeStatusEvent(value []byte) (*models.StatusEvent, error) {
+	var event *models.StatusEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return nil, Permanent("json_unmarshal", err)
+	}
+	if err := subs.ValidateStatusEvent(event); err != nil {
+		return nil, Permanent("validation", err)
+	}
+	return event, nil
+}
+
+// NewStatusHandler возвращает обработчик событий смены статуса заказа.
+// Недопустимый переход, неизвестный заказ и невалидное событие — неустранимые ошибки.
+func NewStatusHandler(handler *subs.Handler) Handler {
+	return func(ctx context.Context, msg Message) error {
+		event, err := decodeStatusEvent(msg.Value)
+		if err != nil {
+			return err
+		}
+		err = handler.ChangeStatus(ctx, event)
+		switch {
+		case errors.Is(err, subs.ErrInvalidTransition):
+			return Permanent("invalid_transition", err)
+		case errors.Is(err, subs.ErrNotFound):
+			return Permanent("not_found", err)
+		case errors.Is(err, subs.ErrValidation):
+			return Permanent("validation", err)
+		}
+		return err
+	}
+}
@@ -0,0 +1,128 @@
+package messaging
+
+import (
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// partitionKey — партиция топика; потребитель может читать одинаковые номера партиций разных топиков
+type partitionKey struct {
+	topic     string
+	partition int
+}
+
+func partitionOf(msg kafka.Message) partitionKey {
+	return partitionKey{topic: msg.Topic, partition: msg.Partition}
+}
+
+// offsetTracker отслеживает сообщения, находящиеся в обработке, и определяет,
+// до какого смещения в каждой партиции можно безопасно сделать commit:
+// смещение подтверждается только когда обработаны все более ранние сообщения партиции.
+type offsetTracker struct {
+	mu         sync.Mutex
+	partitions map[partitionKey]*partitionOffsets
+	// epoch увеличивается при каждой перебалансировке
+	epoch uint64
+}
+
+// offsetCommit — сообщение, до которого можно подтвердить смещение партиции, и
+// поколение назначения партиций, в котором оно обработано
+type offsetCommit struct {
+	kafka.Message
+	epoch uint64
+}
+
+type partitionOffsets struct {
+	queue   []*trackedOffset
+	byValue map[int64]*trackedOffset
+}
+
+type trackedOffset struct {
+	msg  kafka.Message
+	done bool
+}
+
+func newOffsetTracker() *offsetTracker {
+	return &offsetTracker{
+		partitions: make(map[partitionKey]*partitionOffsets),
+	}
+}
+
+// Add регистрирует полученное сообщение. Сообщения одной партиции должны добавляться
+// в порядке возрастания смещений; если смещение не больше последнего добавленного
+// (перебалансировка или повторное чтение), состояние партиции сбрасывается.
+func (t *offsetTracker) Add(msg kafka.Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[partitionOf(msg)]
+	if !ok || (len(p.queue) > 0 && msg.Offset <= p.queue[len(p.queue)-1].msg.Offset) {
+		p = &partitionOffsets{byValue: make(map[int64]*trackedOffset)}
+		t.partitions[partitionOf(msg)] = p
+	}
+	entry := &trackedOffset{msg: msg}
+	p.queue = append(p.queue, entry)
+	p.byValue[msg.Offset] = entry
+}
+
+// Done отмечает сообщение обработанным и возвращает последнее сообщение непрерывного
+// обработанного префикса партиции, если этот префикс сдвинулся. Сообщения партиций,
+// отозванных после их получения, не подтверждаются.
+func (t *offsetTracker) Done(msg kafka.Message) (offsetCommit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.partitions[partitionOf(msg)]
+	if !ok {
+		return offsetCommit{}, false
+	}
+	entry, ok := p.byValue[msg.Offset]
+	if !ok {
+		return offsetCommit{}, false
+	}
+	entry.done = true
+
+	var (
+		last      kafka.Message
+		committed bool
+	)
+	for len(p.queue) > 0 && p.queue[0].done {
+		last = p.queue[0].msg
+		committed = true
+		delete(p.byValue, last.Offset)
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+	}
+	return offsetCommit{Message: last, epoch: t.epoch}, committed
+}
+
+// Revoke сбрасывает состояние всех партиций. kafka-go при перебалансировке отзывает все
+// партиции, и смещения сообщений, полученных до неё, подтверждать уже нельзя: партицию
+// может читать новый владелец, и его подтверждённое смещение откатилось бы назад.
+func (t *offsetTracker) Revoke() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partitions = make(map[partitionKey]*partitionOffsets)
+	t.epoch++
+}
+
+// Epoch возвращает текущее поколение назначения партиций
+func (t *offsetTracker) Epoch() uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.epoch
+}
+
+// Pending возвращает число сообщений, ещё не вошедших в подтверждённый префикс
+func (t *offsetTracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := 0
+	for _, p := range t.partitions {
+		pending += len(p.queue)
+	}
+	return pending
+}
@@ -0,0 +1,101 @@
+package messaging
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOffsetTracker_CommitsContiguousPrefix тестирует, что смещение подтверждается
+// только после обработки всех более ранних сообщений партиции
+func TestOffsetTracker_CommitsContiguousPrefix(t *testing.T) {
+	tracker := newOffsetTracker()
+	msgs := []kafka.Message{
+		{Partition: 0, Offset: 10},
+		{Partition: 0, Offset: 11},
+		{Partition: 0, Offset: 12},
+	}
+	for _, msg := range msgs {
+		tracker.Add(msg)
+	}
+
+	_, ok := tracker.Done(msgs[1])
+	assert.False(t, ok, "offset 11 must wait for offset 10")
+	_, ok = tracker.Done(msgs[2])
+	assert.False(t, ok, "offset 12 must wait for offset 10")
+	assert.Equal(t, 3, tracker.Pending())
+
+	commit, ok := tracker.Done(msgs[0])
+	assert.True(t, ok)
+	assert.Equal(t, int64(12), commit.Offset)
+	assert.Equal(t, 0, tracker.Pending())
+}
+
+// TestOffsetTracker_PartitionsAreIndependent тестирует независимость партиций
+func TestOffsetTracker_PartitionsAreIndependent(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.Add(kafka.Message{Partition: 0, Offset: 5})
+	tracker.Add(kafka.Message{Partition: 1, Offset: 7})
+
+	commit, ok := tracker.Done(kafka.Message{Partition: 1, Offset: 7})
+	assert.True(t, ok)
+	assert.Equal(t, 1, commit.Partition)
+	assert.Equal(t, int64(7), commit.Offset)
+	assert.Equal(t, 1, tracker.Pending())
+}
+
+// TestOffsetTracker_ResetOnRewind тестирует сброс состояния партиции при повторном чтении
+func TestOffsetTracker_ResetOnRewind(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.Add(kafka.Message{Partition: 0, Offset: 20})
+	tracker.Add(kafka.Message{Partition: 0, Offset: 21})
+
+	// После перебалансировки партиция читается заново с подтверждённого смещения
+	tracker.Add(kafka.Message{Partition: 0, Offset: 20})
+
+	assert.Equal(t, 1, tracker.Pending())
+	commit, ok := tracker.Done(kafka.Message{Partition: 0, Offset: 20})
+	assert.True(t, ok)
+	assert.Equal(t, int64(20), commit.Offset)
+
+	_, ok = tracker.Done(kafka.Message{Partition: 0, Offset: 21})
+	assert.False(t, ok, "offsets from before the rewind are ignored")
+}
+
+// TestOffsetTracker_TopicsAreIndependent тестирует, что одинаковые номера партиций
+// разных топиков не смешиваются
+func TestOffsetTracker_TopicsAreIndependent(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.Add(kafka.Message{Topic: "orders", Partition: 0, Offset: 30})
+	tracker.Add(kafka.Message{Topic: "payments", Partition: 0, Offset: 3})
+
+	assert.Equal(t, 2, tracker.Pending())
+	commit, ok := tracker.Done(kafka.Message{Topic: "orders", Partition: 0, Offset: 30})
+	assert.True(t, ok)
+	assert.Equal(t, "orders", commit.Topic)
+	assert.Equal(t, 1, tracker.Pending())
+}
+
+// TestOffsetTracker_Revoke тестирует, что после перебалансировки смещения сообщений,
+// полученных до неё, не подтверждаются
+func TestOffsetTracker_Revoke(t *testing.T) {
+	tracker := newOffsetTracker()
+	tracker.Add(kafka.Message{Partition: 0, Offset: 40})
+	tracker.Add(kafka.Message{Partition: 0, Offset: 41})
+	before, ok := tracker.Done(kafka.Message{Partition: 0, Offset: 40})
+	assert.True(t, ok)
+
+	tracker.Revoke()
+	assert.Equal(t, 0, tracker.Pending())
+	assert.NotEqual(t, tracker.Epoch(), before.epoch, "commit made before the rebalance must be dropped")
+	_, ok = tracker.Done(kafka.Message{Partition: 0, Offset: 41})
+	assert.False(t, ok, "message fetched before the rebalance must not be committed")
+
+	// Сообщения, полученные после перебалансировки, подтверждаются в новом поколении
+	tracker.Add(kafka.Message{Partition: 0, Offset: 35})
+	commit, ok := tracker.Done(kafka.Message{Partition: 0, Offset: 35})
+	assert.True(t, ok)
+	assert.Equal(t, int64(35), commit.Offset)
+	assert.Equal(t, tracker.Epoch(), commit.epoch)
+}
@@ -0,0 +1,68 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaProducer реализует Producer для отправки сообщений в Kafka
+type kafkaProducer struct {
+	writer *kafka.Writer
+	name   string
+}
+
+// NewKafkaProducer создает новый экземпляр kafkaProducer
+func NewKafkaProducer(brokers []string) Producer {
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		BatchTimeout:           10 * time.Millisecond,
+		WriteTimeout:           10 * time.Second,
+	}
+	return &kafkaProducer{
+		writer: writer,
+		name:   "kafka producer",
+	}
+}
+
+// ProduceMessage отправляет сообщение в указанный топик Kafka
+func (p *kafkaProducer) ProduceMessage(ctx context.Context, topic string, msg Message) error {
+	kafkaMsg := kafka.Message{
+		Topic:   topic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: toKafkaHeaders(msg.Headers),
+	}
+	return p.writer.WriteMessages(ctx, kafkaMsg)
+}
+
+// Close закрывает соединение с Kafka
+func (p *kafkaProducer) Close(_ context.Context) error {
+	return p.writer.Close()
+}
+
+// Name возвращает имя ресурса для логирования
+func (p *kafkaProducer) Name() string { return p.name }
+
+func toKafkaHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	result := make([]kafka.Header, 0, len(headers))
+	for key, value := range headers {
+		result = append(result, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	return result
+}
+
+func fromKafkaHeaders(headers []kafka.Header) map[string]string {
+	result := make(map[string]string, len(headers))
+	for _, header := range headers {
+		result[header.Key] = string(header.Value)
+	}
+	return result
+}
@@ -0,0 +1,151 @@
+package messaging
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"orders/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Заголовки сообщения, ожидающего повторной обработки в retry-топике
+const (
+	// HeaderRetryCount — сколько раз сообщение откладывалось в retry-топики
+	HeaderRetryCount = "x-retry-count"
+	// HeaderRetryNotBefore — время (RFC 3339), раньше которого сообщение не обрабатывается
+	HeaderRetryNotBefore = "x-retry-not-before"
+	// HeaderRetryError — последняя временная ошибка обработки
+	HeaderRetryError = "x-retry-error"
+)
+
+// Backoff — политика пауз между повторами обработки при временной ошибке
+type Backoff struct {
+	// Initial — пауза перед первым повтором
+	Initial time.Duration
+	// Max — максимальная пауза; 0 — без ограничения
+	Max time.Duration
+	// Multiplier — во сколько раз растёт пауза с каждой попыткой
+	Multiplier float64
+	// Jitter — случайное отклонение паузы в долях от неё: 0.2 — ±20%
+	Jitter float64
+	// MaxElapsed — максимальное время повторов одного сообщения внутри потребителя;
+	// 0 — ограничено только числом попыток
+	MaxElapsed time.Duration
+}
+
+// Delay возвращает паузу после attempt-й неудачной попытки
+func (b Backoff) Delay(attempt int) time.Duration {
+	delay := float64(b.Initial) * math.Pow(b.Multiplier, float64(attempt-1))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+func (b Backoff) withDefaults() Backoff {
+	if b.Initial <= 0 {
+		b.Initial = 500 * time.Millisecond
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = 2
+	}
+	return b
+}
+
+// RetryTier — топик, в котором сообщение ждёт Delay перед следующей серией попыток
+type RetryTier struct {
+	Topic string
+	Delay time.Duration
+}
+
+// ForRetryTier возвращает конфигурацию потребителя i-го retry-топика. Сообщения,
+// не обработанные им, переходят в следующий retry-топик, после последнего — в DLQ.
+// У каждого retry-топика своя группа потребителей.
+func (cfg ConsumerConfig) ForRetryTier(i int) ConsumerConfig {
+	topic := cfg.RetryTiers[i].Topic
+	cfg.Topics = []string{topic}
+	cfg.GroupID = cfg.GroupID + "-" + topic
+	cfg.retryTier = i + 1
+	return cfg
+}
+
+// sourceTopic возвращает топик, из которого сообщение пришло в сервис: для retry-топиков —
+// исходный топик из заголовка, чтобы Router выбрал тот же обработчик
+func (c *KafkaConsumer) sourceTopic(kafkaMsg kafka.Message, headers map[string]string) string {
+	if c.retryTier > 0 && headers[HeaderOriginalTopic] != "" {
+		return headers[HeaderOriginalTopic]
+	}
+	return kafkaMsg.Topic
+}
+
+// waitRetryTime ждёт времени повторной обработки сообщения из retry-топика.
+// Возвращает false, если ожидание прервано остановкой потребителя.
+func (c *KafkaConsumer) waitRetryTime(ctx context.Context, kafkaMsg kafka.Message) bool {
+	if c.retryTier == 0 {
+		return true
+	}
+	notBefore := kafkaMsg.Time.Add(c.retryTiers[c.retryTier-1].Delay)
+	if value := fromKafkaHeaders(kafkaMsg.Headers)[HeaderRetryNotBefore]; value != "" {
+		if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			notBefore = t
+		}
+	}
+	if wait := time.Until(notBefore); wait > 0 {
+		return sleepCtx(ctx, wait)
+	}
+	return true
+}
+
+// deferRetry перекладывает сообщение, не обработанное из-за временной ошибки, в следующий
+// retry-топик, чтобы не задерживать партицию. Если retry-топики исчерпаны, сообщение
+// отправляется в DLQ. Возвращает false, только если отправка прервана остановкой потребителя.
+func (c *KafkaConsumer) deferRetry(ctx context.Context, log *logrus.Entry, kafkaMsg kafka.Message, err error) bool {
+	if c.retryTier >= len(c.retryTiers) {
+		metrics.KafkaMessagesTotal.WithLabelValues(kafkaMsg.Topic, "error", "retries_exhausted").Inc()
+		return c.handlePermanentErr(ctx, log, kafkaMsg, "retries_exhausted", err)
+	}
+
+	tier := c.retryTiers[c.retryTier]
+	retryMsg := newRetryMessage(kafkaMsg, tier, err)
+	log = log.WithFields(logrus.Fields{"retry_topic": tier.Topic, "retry_count": retryMsg.Headers[HeaderRetryCount]})
+	for {
+		// Retry-топики пишутся тем же producer, что и DLQ
+		publishErr := c.dlq.ProduceMessage(ctx, tier.Topic, retryMsg)
+		if publishErr == nil {
+			break
+		}
+		log.Errorf("Failed to publish message to retry topic, retrying: %v", publishErr)
+		if !sleepCtx(ctx, dlqRetryInterval) {
+			return false
+		}
+	}
+	metrics.KafkaRetriedMessagesTotal.WithLabelValues(kafkaMsg.Topic, tier.Topic).Inc()
+	log.Warnf("Temporary error - message deferred for %s: %v", tier.Delay, err)
+	return true
+}
+
+// newRetryMessage формирует сообщение для retry-топика. Заголовки x-original-*
+// указывают на топик, в который сообщение пришло в сервис впервые.
+func newRetryMessage(msg kafka.Message, tier RetryTier, err error) Message {
+	headers := fromKafkaHeaders(msg.Headers)
+	if headers[HeaderOriginalTopic] == "" {
+		setOriginHeaders(headers, msg)
+	}
+	retryCount, _ := strconv.Atoi(headers[HeaderRetryCount])
+	headers[HeaderRetryCount] = strconv.Itoa(retryCount + 1)
+	headers[HeaderRetryNotBefore] = time.Now().Add(tier.Delay).UTC().Format(time.RFC3339Nano)
+	headers[HeaderRetryError] = err.Error()
+
+	return Message{
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	}
+}
@@ -0,0 +1,99 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
+	assert.Equal(t, 100*time.Millisecond, b.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, b.Delay(2))
+	assert.Equal(t, 800*time.Millisecond, b.Delay(4))
+	assert.Equal(t, time.Second, b.Delay(10))
+}
+
+func TestBackoff_DelayJitter(t *testing.T) {
+	b := Backoff{Initial: time.Second, Multiplier: 2, Jitter: 0.2}
+	for range 100 {
+		delay := b.Delay(2)
+		assert.GreaterOrEqual(t, delay, 1600*time.Millisecond)
+		assert.LessOrEqual(t, delay, 2400*time.Millisecond)
+	}
+}
+
+var testRetryTiers = []RetryTier{
+	{Topic: "orders.retry-5s", Delay: 5 * time.Second},
+	{Topic: "orders.retry-1m", Delay: time.Minute},
+}
+
+func TestForRetryTier(t *testing.T) {
+	cfg := ConsumerConfig{Topics: []string{"orders"}, GroupID: "orders-group", RetryTiers: testRetryTiers}.ForRetryTier(1)
+
+	assert.Equal(t, []string{"orders.retry-1m"}, cfg.Topics)
+	assert.Equal(t, "orders-group-orders.retry-1m", cfg.GroupID)
+	assert.Equal(t, 2, cfg.retryTier)
+}
+
+func TestNewRetryMessage(t *testing.T) {
+	msg := typedMessage("orders", "order.created")
+	msg.Offset = 42
+	tier := testRetryTiers[0]
+
+	retryMsg := newRetryMessage(msg, tier, errors.New("connection refused"))
+	assert.Equal(t, "orders", retryMsg.Headers[HeaderOriginalTopic])
+	assert.Equal(t, "42", retryMsg.Headers[HeaderOriginalOffset])
+	assert.Equal(t, "order.created", retryMsg.Headers[HeaderEventType])
+	assert.Equal(t, "1", retryMsg.Headers[HeaderRetryCount])
+	assert.Equal(t, "connection refused", retryMsg.Headers[HeaderRetryError])
+	notBefore, err := time.Parse(time.RFC3339Nano, retryMsg.Headers[HeaderRetryNotBefore])
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(tier.Delay), notBefore, time.Second)
+
+	// Повтор из retry-топика сохраняет исходный топик и увеличивает счётчик
+	next := newRetryMessage(kafka.Message{
+		Topic:   tier.Topic,
+		Key:     retryMsg.Key,
+		Value:   retryMsg.Value,
+		Headers: toKafkaHeaders(retryMsg.Headers),
+	}, testRetryTiers[1], errors.New("timeout"))
+	assert.Equal(t, "orders", next.Headers[HeaderOriginalTopic])
+	assert.Equal(t, "2", next.Headers[HeaderRetryCount])
+	assert.Equal(t, "timeout", next.Headers[HeaderRetryError])
+}
+
+// TestDeferRetry тестирует переход сообщения по retry-топикам и в DLQ после последнего
+func TestDeferRetry(t *testing.T) {
+	dlq := &recordingProducer{}
+	log := logrus.NewEntry(testLogger())
+	msg := typedMessage("orders", "order.created")
+
+	for tier, want := range []string{"orders.retry-5s", "orders.retry-1m", "orders.dlq"} {
+		c := &KafkaConsumer{logger: testLogger(), dlq: dlq, dlqTopic: "orders.dlq", retryTiers: testRetryTiers, retryTier: tier}
+		require.True(t, c.deferRetry(context.Background(), log, msg, errors.New("connection refused")))
+
+		require.Len(t, dlq.topics, tier+1)
+		assert.Equal(t, want, dlq.topics[tier])
+		sent := dlq.sent[tier]
+		assert.Equal(t, "orders", sent.Headers[HeaderOriginalTopic])
+		msg = kafka.Message{Topic: want, Key: sent.Key, Value: sent.Value, Headers: toKafkaHeaders(sent.Headers)}
+	}
+	assert.Equal(t, "retries_exhausted", dlq.sent[2].Headers[HeaderErrorType])
+	assert.Equal(t, "2", dlq.sent[2].Headers[HeaderRetryCount])
+}
+
+func TestSourceTopic(t *testing.T) {
+	headers := map[string]string{HeaderOriginalTopic: "orders"}
+	msg := kafka.Message{Topic: "orders.retry-5s"}
+
+	assert.Equal(t, "orders", (&KafkaConsumer{retryTier: 1}).sourceTopic(msg, headers))
+	assert.Equal(t, "orders.retry-5s", (&KafkaConsumer{}).sourceTopic(msg, headers))
+}
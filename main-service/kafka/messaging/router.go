@@ -0,0 +1,114 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Политики обработки сообщений, для которых не зарегистрирован обработчик
+const (
+	// UnknownEventDLQ — сообщение отправляется в DLQ с типом ошибки unknown_event
+	UnknownEventDLQ = "dlq"
+	// UnknownEventSkip — сообщение записывается в лог и подтверждается
+	UnknownEventSkip = "skip"
+)
+
+// PermanentError — ошибка обработчика, после которой сообщение нельзя обработать повторно;
+// такое сообщение отправляется в DLQ с типом Type
+type PermanentError struct {
+	Type string
+	Err  error
+}
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// Permanent помечает ошибку обработчика как неустранимую повтором
+func Permanent(errType string, err error) error {
+	return &PermanentError{Type: errType, Err: err}
+}
+
+// routeKey — топик и тип события; пустой топик подходит для любого топика
+type routeKey struct {
+	topic     string
+	eventType string
+}
+
+// route — обработчик сообщения. Маршрут orders направляет сообщение в пакетную
+// запись заказов потребителя вместо handler.
+type route struct {
+	handler Handler
+	orders  bool
+}
+
+// Router выбирает обработчик сообщения по топику и заголовку HeaderEventType.
+// Сначала ищется обработчик для топика сообщения, затем — зарегистрированный для всех
+// топиков; если обработчика нет, сообщение передаётся fallback.
+type Router struct {
+	routes   map[routeKey]route
+	fallback Handler
+}
+
+// NewRouter создает Router без маршрутов; неизвестные сообщения отправляются в DLQ
+func NewRouter() *Router {
+	return &Router{
+		routes:   make(map[routeKey]route),
+		fallback: RejectUnknown,
+	}
+}
+
+// Handle регистрирует обработчик сообщений типа eventType из топика topic
+// (пустой topic — из любого топика; пустой eventType — сообщения без заголовка типа)
+func (r *Router) Handle(topic, eventType string, handler Handler) {
+	r.routes[routeKey{topic, eventType}] = route{handler: handler}
+}
+
+// HandleOrders направляет сообщения типа eventType из топика topic в пакетную запись заказов:
+// они сохраняются пачками одной транзакцией, а порядок с остальными сообщениями сохраняется
+func (r *Router) HandleOrders(topic, eventType string) {
+	r.routes[routeKey{topic, eventType}] = route{orders: true}
+}
+
+// SetFallback задаёт обработчик сообщений, для которых нет маршрута
+func (r *Router) SetFallback(handler Handler) {
+	r.fallback = handler
+}
+
+// route возвращает маршрут сообщения
+func (r *Router) route(topic string, headers map[string]string) route {
+	eventType := headers[HeaderEventType]
+	if rt, ok := r.routes[routeKey{topic, eventType}]; ok {
+		return rt
+	}
+	if rt, ok := r.routes[routeKey{"", eventType}]; ok {
+		return rt
+	}
+	return route{handler: r.fallback}
+}
+
+// RejectUnknown — fallback, отправляющий сообщение неизвестного типа в DLQ
+func RejectUnknown(_ context.Context, msg Message) error {
+	return Permanent("unknown_event", fmt.Errorf("unknown event type %q", msg.Headers[HeaderEventType]))
+}
+
+// SkipUnknown возвращает fallback, который записывает сообщение неизвестного типа в лог и подтверждает его
+func SkipUnknown(logger *logrus.Logger) Handler {
+	return func(_ context.Context, msg Message) error {
+		logger.WithField("key", string(msg.Key)).
+			Warnf("SkipUnknown: skipping message with unknown event type %q", msg.Headers[HeaderEventType])
+		return nil
+	}
+}
+
+// ParseUnknownEventPolicy возвращает fallback для политики dlq или skip
+func ParseUnknownEventPolicy(policy string, logger *logrus.Logger) (Handler, error) {
+	switch policy {
+	case UnknownEventDLQ:
+		return RejectUnknown, nil
+	case UnknownEventSkip:
+		return SkipUnknown(logger), nil
+	}
+	return nil, fmt.Errorf("unknown event policy %q: expected %s or %s", policy, UnknownEventDLQ, UnknownEventSkip)
+}
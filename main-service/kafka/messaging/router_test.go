@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProducer запоминает отправленные сообщения и их топики
+type recordingProducer struct {
+	mu     sync.Mutex
+	sent   []Message
+	topics []string
+}
+
+func (p *recordingProducer) ProduceMessage(_ context.Context, topic string, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, msg)
+	p.topics = append(p.topics, topic)
+	return nil
+}
+
+func (p *recordingProducer) Close(context.Context) error { return nil }
+func (p *recordingProducer) Name() string                { return "recording producer" }
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return logger
+}
+
+func typedMessage(topic, eventType string) kafka.Message {
+	return kafka.Message{
+		Topic:   topic,
+		Key:     []byte("b563feb7b2b84b6test"),
+		Value:   []byte(`{}`),
+		Headers: toKafkaHeaders(map[string]string{HeaderEventType: eventType}),
+	}
+}
+
+// TestRouter_Route тестирует выбор обработчика: сначала по топику, затем для всех топиков
+func TestRouter_Route(t *testing.T) {
+	var called string
+	handler := func(name string) Handler {
+		return func(context.Context, Message) error {
+			called = name
+			return nil
+		}
+	}
+	router := NewRouter()
+	router.HandleOrders("", "")
+	router.Handle("", "order.cancelled", handler("any topic"))
+	router.Handle("payments", "order.cancelled", handler("payments"))
+	router.SetFallback(handler("fallback"))
+
+	assert.True(t, router.route("orders", map[string]string{}).orders)
+
+	for topic, want := range map[string]string{"orders": "any topic", "payments": "payments"} {
+		rt := router.route(topic, map[string]string{HeaderEventType: "order.cancelled"})
+		require.False(t, rt.orders)
+		require.NoError(t, rt.handler(context.Background(), Message{}))
+		assert.Equal(t, want, called)
+	}
+
+	require.NoError(t, router.route("orders", map[string]string{HeaderEventType: "payment.refunded"}).handler(context.Background(), Message{}))
+	assert.Equal(t, "fallback", called)
+}
+
+func TestParseUnknownEventPolicy(t *testing.T) {
+	reject, err := ParseUnknownEventPolicy(UnknownEventDLQ, testLogger())
+	require.NoError(t, err)
+	var permanent *PermanentError
+	require.ErrorAs(t, reject(context.Background(), Message{}), &permanent)
+	assert.Equal(t, "unknown_event", permanent.Type)
+
+	skip, err := ParseUnknownEventPolicy(UnknownEventSkip, testLogger())
+	require.NoError(t, err)
+	assert.NoError(t, skip(context.Background(), Message{}))
+
+	_, err = ParseUnknownEventPolicy("drop", testLogger())
+	assert.Error(t, err)
+}
+
+// TestProcessMessage_Permanent тестирует отправку в DLQ при неустранимой ошибке обработчика
+func TestProcessMessage_Permanent(t *testing.T) {
+	dlq := &recordingProducer{}
+	c := &KafkaConsumer{logger: testLogger(), dlq: dlq, dlqTopic: "orders.dlq", maxRetries: 3}
+	handler := func(_ context.Context, msg Message) error {
+		assert.Equal(t, "payment.refunded", msg.Headers[HeaderEventType])
+		return Permanent("validation", errors.New("amount is negative"))
+	}
+
+	require.True(t, c.processMessage(context.Background(), typedMessage("payments", "payment.refunded"), handler, time.Now()))
+
+	require.Len(t, dlq.sent, 1)
+	assert.Equal(t, "validation", dlq.sent[0].Headers[HeaderErrorType])
+	assert.Equal(t, "amount is negative", dlq.sent[0].Headers[HeaderErrorMessage])
+	assert.Equal(t, "payments", dlq.sent[0].Headers[HeaderOriginalTopic])
+}
+
+// TestProcessMessage_UnclassifiedError тестирует отправку в DLQ при обычной ошибке обработчика
+func TestProcessMessage_UnclassifiedError(t *testing.T) {
+	dlq := &recordingProducer{}
+	c := &KafkaConsumer{logger: testLogger(), dlq: dlq, dlqTopic: "orders.dlq", maxRetries: 3}
+	handler := func(context.Context, Message) error {
+		return errors.New("unexpected")
+	}
+
+	require.True(t, c.processMessage(context.Background(), typedMessage("payments", "payment.refunded"), handler, time.Now()))
+
+	require.Len(t, dlq.sent, 1)
+	assert.Equal(t, []string{"orders.dlq"}, dlq.topics)
+	assert.Equal(t, "processing", dlq.sent[0].Headers[HeaderErrorType])
+	assert.Equal(t, "unexpected", dlq.sent[0].Headers[HeaderErrorMessage])
+}
+
+// TestStatusHandler_InvalidJSON тестирует тип ошибки для нечитаемого события смены статуса
+func TestStatusHandler_InvalidJSON(t *testing.T) {
+	err := NewStatusHandler(nil)(context.Background(), Message{Value: []byte(`{`)})
+
+	var permanent *PermanentError
+	require.ErrorAs(t, err, &permanent)
+	assert.Equal(t, "json_unmarshal", permanent.Type)
+}
@@ -0,0 +1,220 @@
+package messaging
+
+import (
+	"context"
+	"orders/internal/metrics"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// defaultStatsInterval — период чтения kafka.Reader.Stats() по умолчанию
+const defaultStatsInterval = 15 * time.Second
+
+// partitionStats ведёт метрики партиций, назначенных потребителю группы.
+// kafka.Reader группы не сообщает назначенные партиции и их смещения, поэтому они
+// определяются по полученным сообщениям: партиция считается назначенной с первого
+// сообщения из неё после перебалансировки. kafka-go при перебалансировке отзывает все
+// партиции, поэтому после неё метрики партиций удаляются до следующего сообщения.
+// High watermark назначенных партиций дополнительно обновляется по таймеру
+// (KafkaConsumer.refreshWatermarks), поэтому отставание растёт, даже когда сообщения не читаются.
+type partitionStats struct {
+	mu         sync.Mutex
+	group      string
+	partitions map[partitionKey]*partitionState
+}
+
+type partitionState struct {
+	offset        int64
+	committed     int64
+	highWatermark int64
+}
+
+func (s *partitionState) lag() int64 {
+	return max(s.highWatermark-s.committed, 0)
+}
+
+func newPartitionStats(group string) *partitionStats {
+	return &partitionStats{
+		group:      group,
+		partitions: make(map[partitionKey]*partitionState),
+	}
+}
+
+// Fetched учитывает полученное сообщение: смещение и high watermark его партиции
+func (s *partitionStats) Fetched(msg kafka.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := partitionOf(msg)
+	state, ok := s.partitions[key]
+	if !ok {
+		// Чтение партиции начинается с подтверждённого смещения
+		state = &partitionState{committed: msg.Offset}
+		s.partitions[key] = state
+		metrics.KafkaConsumerAssignmentChangesTotal.WithLabelValues(s.group, "assigned").Inc()
+		metrics.KafkaConsumerAssignedPartitions.WithLabelValues(s.group).Set(float64(len(s.partitions)))
+	}
+	state.offset = msg.Offset
+	state.highWatermark = msg.HighWaterMark
+	s.observe(key, state)
+}
+
+// SetHighWatermark обновляет high watermark назначенной партиции, полученный от брокера
+func (s *partitionStats) SetHighWatermark(topic string, partition int, highWatermark int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := partitionKey{topic: topic, partition: partition}
+	state, ok := s.partitions[key]
+	if !ok {
+		// Партиция отозвана, пока запрашивался high watermark
+		return
+	}
+	state.highWatermark = max(state.highWatermark, highWatermark)
+	s.observe(key, state)
+}
+
+// Assigned возвращает назначенные партиции по топикам
+func (s *partitionStats) Assigned() map[string][]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assigned := make(map[string][]int)
+	for key := range s.partitions {
+		assigned[key.topic] = append(assigned[key.topic], key.partition)
+	}
+	return assigned
+}
+
+// Committed учитывает подтверждённое смещение партиции
+func (s *partitionStats) Committed(msg kafka.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := partitionOf(msg)
+	state, ok := s.partitions[key]
+	if !ok {
+		// Партиция отозвана до подтверждения
+		return
+	}
+	state.committed = max(state.committed, msg.Offset+1)
+	s.observe(key, state)
+}
+
+// Rebalanced учитывает rebalances перебалансировок с прошлого чтения kafka.Reader.Stats()
+// и отзывает все партиции
+func (s *partitionStats) Rebalanced(rebalances int64) {
+	if rebalances <= 0 {
+		return
+	}
+	metrics.KafkaConsumerRebalancesTotal.WithLabelValues(s.group).Add(float64(rebalances))
+	s.RevokeAll()
+}
+
+// RevokeAll удаляет метрики всех партиций, например при выходе из группы
+func (s *partitionStats) RevokeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.partitions {
+		labels := []string{s.group, key.topic, strconv.Itoa(key.partition)}
+		metrics.KafkaConsumerLag.DeleteLabelValues(labels...)
+		metrics.KafkaConsumerOffset.DeleteLabelValues(labels...)
+		metrics.KafkaConsumerCommittedOffset.DeleteLabelValues(labels...)
+		metrics.KafkaConsumerHighWatermark.DeleteLabelValues(labels...)
+		metrics.KafkaConsumerAssignmentChangesTotal.WithLabelValues(s.group, "revoked").Inc()
+		delete(s.partitions, key)
+	}
+	metrics.KafkaConsumerAssignedPartitions.WithLabelValues(s.group).Set(0)
+}
+
+// Lag возвращает суммарное отставание подтверждённых смещений по назначенным партициям
+func (s *partitionStats) Lag() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lag int64
+	for _, state := range s.partitions {
+		lag += state.lag()
+	}
+	return lag
+}
+
+func (s *partitionStats) observe(key partitionKey, state *partitionState) {
+	labels := []string{s.group, key.topic, strconv.Itoa(key.partition)}
+	metrics.KafkaConsumerLag.WithLabelValues(labels...).Set(float64(state.lag()))
+	metrics.KafkaConsumerOffset.WithLabelValues(labels...).Set(float64(state.offset))
+	metrics.KafkaConsumerCommittedOffset.WithLabelValues(labels...).Set(float64(state.committed))
+	metrics.KafkaConsumerHighWatermark.WithLabelValues(labels...).Set(float64(state.highWatermark))
+}
+
+// watermarkSource возвращает смещения партиций; реализуется kafka.Client
+type watermarkSource interface {
+	ListOffsets(ctx context.Context, req *kafka.ListOffsetsRequest) (*kafka.ListOffsetsResponse, error)
+}
+
+// collectStats периодически проверяет перебалансировку и обновляет high watermark
+// назначенных партиций. Счётчики Stats() обнуляются при каждом чтении, поэтому
+// потребитель читает её только в checkRebalance.
+func (c *KafkaConsumer) collectStats(ctx context.Context) {
+	ticker := time.NewTicker(c.statsInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkRebalance()
+			c.refreshWatermarks(ctx)
+		}
+	}
+}
+
+// checkRebalance читает kafka.Reader.Stats() и после перебалансировки отзывает партиции:
+// удаляет их метрики и отбрасывает ещё не подтверждённые смещения. Вызывается по таймеру
+// и перед каждым подтверждением смещений; Stats() сообщает о перебалансировке только
+// одному из вызывающих.
+func (c *KafkaConsumer) checkRebalance() {
+	rebalances := c.reader.Stats().Rebalances
+	if rebalances <= 0 {
+		return
+	}
+	c.tracker.Revoke()
+	c.partitions.Rebalanced(rebalances)
+}
+
+// refreshWatermarks запрашивает у брокера high watermark назначенных партиций.
+// Без этого отставание обновлялось бы только по полученным сообщениям и замирало бы,
+// когда потребитель перестаёт их читать.
+func (c *KafkaConsumer) refreshWatermarks(ctx context.Context) {
+	assigned := c.partitions.Assigned()
+	if len(assigned) == 0 {
+		return
+	}
+	req := &kafka.ListOffsetsRequest{Topics: make(map[string][]kafka.OffsetRequest, len(assigned))}
+	for topic, partitions := range assigned {
+		for _, partition := range partitions {
+			req.Topics[topic] = append(req.Topics[topic], kafka.LastOffsetOf(partition))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.statsInterval)
+	defer cancel()
+	resp, err := c.watermarks.ListOffsets(ctx, req)
+	if err != nil {
+		c.logger.Warnf("KafkaConsumer.refreshWatermarks: %v", err)
+		return
+	}
+	for topic, partitions := range resp.Topics {
+		for _, offsets := range partitions {
+			if offsets.Error != nil {
+				c.logger.Warnf("KafkaConsumer.refreshWatermarks: %s/%d: %v", topic, offsets.Partition, offsets.Error)
+				continue
+			}
+			c.partitions.SetHighWatermark(topic, offsets.Partition, offsets.LastOffset)
+		}
+	}
+}
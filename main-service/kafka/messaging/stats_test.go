@@ -0,0 +1,104 @@
+package messaging
+
+import (
+	"context"
+	"orders/internal/metrics"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPartitionStats_Lag тестирует отставание подтверждённого смещения от high watermark
+func TestPartitionStats_Lag(t *testing.T) {
+	stats := newPartitionStats("stats-lag")
+	stats.Fetched(kafka.Message{Topic: "orders", Partition: 0, Offset: 100, HighWaterMark: 150})
+	stats.Fetched(kafka.Message{Topic: "orders", Partition: 1, Offset: 7, HighWaterMark: 10})
+
+	// До подтверждения отставание считается от первого полученного смещения
+	assert.Equal(t, int64(53), stats.Lag())
+	assert.Equal(t, 50.0, testutil.ToFloat64(metrics.KafkaConsumerLag.WithLabelValues("stats-lag", "orders", "0")))
+	assert.Equal(t, 150.0, testutil.ToFloat64(metrics.KafkaConsumerHighWatermark.WithLabelValues("stats-lag", "orders", "0")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.KafkaConsumerAssignedPartitions.WithLabelValues("stats-lag")))
+
+	stats.Fetched(kafka.Message{Topic: "orders", Partition: 0, Offset: 101, HighWaterMark: 150})
+	stats.Committed(kafka.Message{Topic: "orders", Partition: 0, Offset: 101})
+	assert.Equal(t, int64(51), stats.Lag())
+	assert.Equal(t, 48.0, testutil.ToFloat64(metrics.KafkaConsumerLag.WithLabelValues("stats-lag", "orders", "0")))
+	assert.Equal(t, 101.0, testutil.ToFloat64(metrics.KafkaConsumerOffset.WithLabelValues("stats-lag", "orders", "0")))
+	assert.Equal(t, 102.0, testutil.ToFloat64(metrics.KafkaConsumerCommittedOffset.WithLabelValues("stats-lag", "orders", "0")))
+
+	// Запоздавшее подтверждение меньшего смещения не откатывает отставание
+	stats.Committed(kafka.Message{Topic: "orders", Partition: 0, Offset: 100})
+	assert.Equal(t, int64(51), stats.Lag())
+}
+
+// TestPartitionStats_Rebalanced тестирует отзыв партиций при перебалансировке
+func TestPartitionStats_Rebalanced(t *testing.T) {
+	stats := newPartitionStats("stats-rebalance")
+	stats.Fetched(kafka.Message{Topic: "orders", Partition: 0, Offset: 1, HighWaterMark: 5})
+	stats.Fetched(kafka.Message{Topic: "orders", Partition: 1, Offset: 1, HighWaterMark: 5})
+
+	stats.Rebalanced(0)
+	assert.Equal(t, int64(8), stats.Lag())
+
+	stats.Rebalanced(1)
+	assert.Zero(t, stats.Lag())
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.KafkaConsumerRebalancesTotal.WithLabelValues("stats-rebalance")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.KafkaConsumerAssignmentChangesTotal.WithLabelValues("stats-rebalance", "revoked")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.KafkaConsumerAssignedPartitions.WithLabelValues("stats-rebalance")))
+	assert.False(t, metrics.KafkaConsumerLag.DeleteLabelValues("stats-rebalance", "orders", "0"), "revoked partition keeps no series")
+
+	// Партиция, снова назначенная после перебалансировки, появляется с первым сообщением
+	stats.Fetched(kafka.Message{Topic: "orders", Partition: 1, Offset: 2, HighWaterMark: 5})
+	assert.Equal(t, 3.0, testutil.ToFloat64(metrics.KafkaConsumerAssignmentChangesTotal.WithLabelValues("stats-rebalance", "assigned")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.KafkaConsumerAssignedPartitions.WithLabelValues("stats-rebalance")))
+}
+
+// fakeWatermarks возвращает заданные high watermark партиций
+type fakeWatermarks struct {
+	last map[int]int64
+}
+
+func (f *fakeWatermarks) ListOffsets(ctx context.Context, req *kafka.ListOffsetsRequest) (*kafka.ListOffsetsResponse, error) {
+	resp := &kafka.ListOffsetsResponse{Topics: make(map[string][]kafka.PartitionOffsets)}
+	for topic, offsets := range req.Topics {
+		for _, offset := range offsets {
+			resp.Topics[topic] = append(resp.Topics[topic], kafka.PartitionOffsets{
+				Partition:  offset.Partition,
+				LastOffset: f.last[offset.Partition],
+			})
+		}
+	}
+	return resp, nil
+}
+
+// TestKafkaConsumer_RefreshWatermarks тестирует рост отставания, когда потребитель не получает сообщений
+func TestKafkaConsumer_RefreshWatermarks(t *testing.T) {
+	watermarks := &fakeWatermarks{last: map[int]int64{0: 150}}
+	c := &KafkaConsumer{
+		logger:        testLogger(),
+		partitions:    newPartitionStats("stats-refresh"),
+		statsInterval: time.Second,
+		watermarks:    watermarks,
+	}
+	c.partitions.Fetched(kafka.Message{Topic: "orders", Partition: 0, Offset: 100, HighWaterMark: 150})
+	c.partitions.Committed(kafka.Message{Topic: "orders", Partition: 0, Offset: 100})
+	assert.Equal(t, int64(49), c.partitions.Lag())
+
+	// Потребитель стоит, а в партицию продолжают писать
+	watermarks.last[0] = 400
+	c.refreshWatermarks(context.Background())
+	assert.Equal(t, int64(299), c.partitions.Lag())
+	assert.Equal(t, 299.0, testutil.ToFloat64(metrics.KafkaConsumerLag.WithLabelValues("stats-refresh", "orders", "0")))
+	assert.Equal(t, 400.0, testutil.ToFloat64(metrics.KafkaConsumerHighWatermark.WithLabelValues("stats-refresh", "orders", "0")))
+	assert.Equal(t, 101.0, testutil.ToFloat64(metrics.KafkaConsumerCommittedOffset.WithLabelValues("stats-refresh", "orders", "0")))
+
+	// Отозванная партиция не появляется снова по ответу брокера
+	c.partitions.RevokeAll()
+	watermarks.last[0] = 500
+	c.refreshWatermarks(context.Background())
+	assert.Zero(t, c.partitions.Lag())
+}
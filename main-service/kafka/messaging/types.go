@@ -9,14 +9,16 @@ import (
 
 // Message — сообщение
 type Message struct {
-	Key   []byte
-	Value []byte
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
 }
 
 // Producer определяет интерфейс для отправки сообщений в Kafka
 type Producer interface {
 	ProduceMessage(ctx context.Context, topic string, msg Message) error
-	Close() error
+	Close(ctx context.Context) error
+	Name() string
 }
 
 // Handler обрабатывает сообщения из Kafka
@@ -26,7 +28,13 @@ type Handler func(ctx context.Context, msg Message) error
 type Consumer interface {
 	Run(ctx context.Context)
 	ConsumeMessage(ctx context.Context) error
-	Commit(ctx context.Context, msg kafka.Message) error
+	Commit(ctx context.Context, msgs ...kafka.Message) error
+	// Ping проверяет, что хотя бы один брокер доступен
+	Ping(ctx context.Context) error
+	// Lag возвращает число непрочитанных сообщений в назначенных партициях
+	Lag() int64
+	// Running сообщает, запущен ли цикл чтения
+	Running() bool
 	Close(ctx context.Context) error
 	Name() string
 }
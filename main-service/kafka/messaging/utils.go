@@ -2,63 +2,43 @@ package messaging
 
 import (
 	"context"
-	"strings"
+	"errors"
+	"orders/internal/metrics"
+	"orders/internal/subs"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 )
 
-// isTemporaryError определяет временная ошибка или перманентная
-func (c *KafkaConsumer) isTemporaryError(err error) bool {
-	errStr := err.Error()
-
-	// Временные ошибки (можно ретраить)
-	temporaryPatterns := []string{
-		"connection refused",
-		"timeout",
-		"deadline exceeded",
-		"database is locked",
-		"too many connections",
-		"network error",
-		"temporary failure",
-		"try again later",
-	}
-
-	// Перманентные ошибки (не ретраить)
-	permanentPatterns := []string{
-		"validation failed",
-		"invalid",
-		"not found",
-		"duplicate key",
-		"already exists",
-		"permission denied",
-		"constraint",
-	}
-
-	for _, pattern := range temporaryPatterns {
-		if strings.Contains(strings.ToLower(errStr), pattern) {
-			return true
-		}
-	}
-
-	for _, pattern := range permanentPatterns {
-		if strings.Contains(strings.ToLower(errStr), pattern) {
-			return false
-		}
-	}
-
-	return false
+// isTemporaryError определяет, можно ли повторить запись заказа
+func isTemporaryError(err error) bool {
+	return errors.Is(err, subs.ErrTransient)
 }
 
-func (c *KafkaConsumer) handlePermanentErr(ctx context.Context, log *logrus.Entry, msg kafka.Message, errType string, err error) {
-	log.WithFields(
+// handlePermanentErr отправляет необрабатываемое сообщение в dead-letter топик.
+// Пока DLQ недоступен, отправка повторяется; false возвращается только если
+// ожидание прервано остановкой потребителя — тогда сообщение не подтверждается.
+func (c *KafkaConsumer) handlePermanentErr(ctx context.Context, log *logrus.Entry, msg kafka.Message, errType string, err error) bool {
+	log = log.WithFields(
 		logrus.Fields{
 			"error_type": errType,
 			"error":      err.Error(),
-			"message":    string(msg.Value),
-		}).Error("Permanent error - messsage skipped")
-
-	if commitErr := c.reader.CommitMessages(ctx, msg); commitErr != nil {
-		log.Errorf("Failed to commit invalid message: %v", commitErr)
+			"dlq_topic":  c.dlqTopic,
+		})
+
+	dlqMsg := newDLQMessage(msg, errType, err)
+	for {
+		publishErr := c.dlq.ProduceMessage(ctx, c.dlqTopic, dlqMsg)
+		if publishErr == nil {
+			break
+		}
+		metrics.KafkaDeadLetterTotal.WithLabelValues(msg.Topic, errType, "failed").Inc()
+		log.Errorf("Permanent error - failed to publish message to DLQ, retrying: %v", publishErr)
+		if !sleepCtx(ctx, dlqRetryInterval) {
+			return false
+		}
 	}
+	metrics.KafkaDeadLetterTotal.WithLabelValues(msg.Topic, errType, "published").Inc()
+	log.Warn("Permanent error - message sent to DLQ")
+	return true
 }
@@ -48,27 +48,130 @@ func (_m *Cache) Get(key string) (*models.OrderJSON, bool) {
 	return r0, r1
 }
 
+// GetIndex provides a mock function with given fields: key
+func (_m *Cache) GetIndex(key string) ([]string, bool) {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetIndex")
+	}
+
+	var r0 []string
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string) ([]string, bool)); ok {
+		return rf(key)
+	}
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// GetStale provides a mock function with given fields: key
+func (_m *Cache) GetStale(key string) (*models.OrderJSON, bool) {
+	ret := _m.Called(key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStale")
+	}
+
+	var r0 *models.OrderJSON
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(string) (*models.OrderJSON, bool)); ok {
+		return rf(key)
+	}
+	if rf, ok := ret.Get(0).(func(string) *models.OrderJSON); ok {
+		r0 = rf(key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OrderJSON)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// IsMissing provides a mock function with given fields: orderUID
+func (_m *Cache) IsMissing(orderUID string) bool {
+	ret := _m.Called(orderUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsMissing")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(orderUID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // Set provides a mock function with given fields: key, value
 func (_m *Cache) Set(key string, value *models.OrderJSON) {
 	_m.Called(key, value)
 }
 
+// DeleteIndex provides a mock function with given fields: keys
+func (_m *Cache) DeleteIndex(keys []string) {
+	_m.Called(keys)
+}
+
+// SetIndex provides a mock function with given fields: key, orderUIDs
+func (_m *Cache) SetIndex(key string, orderUIDs []string) {
+	_m.Called(key, orderUIDs)
+}
+
+// SetMissing provides a mock function with given fields: orderUID
+func (_m *Cache) SetMissing(orderUID string) {
+	_m.Called(orderUID)
+}
+
 // WarmUpCache provides a mock function with given fields: orders
-func (_m *Cache) WarmUpCache(orders []models.OrderJSON) error {
+func (_m *Cache) WarmUpCache(orders []models.OrderJSON) (int, error) {
 	ret := _m.Called(orders)
 
 	if len(ret) == 0 {
 		panic("no return value specified for WarmUpCache")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func([]models.OrderJSON) error); ok {
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func([]models.OrderJSON) (int, error)); ok {
+		return rf(orders)
+	}
+	if rf, ok := ret.Get(0).(func([]models.OrderJSON) int); ok {
 		r0 = rf(orders)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(int)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func([]models.OrderJSON) error); ok {
+		r1 = rf(orders)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewCache creates a new instance of Cache. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
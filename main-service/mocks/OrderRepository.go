@@ -15,51 +15,70 @@ type OrderRepository struct {
 }
 
 // Create provides a mock function with given fields: ctx, orderJSON
-func (_m *OrderRepository) Create(ctx context.Context, orderJSON *models.OrderJSON) error {
+func (_m *OrderRepository) Create(ctx context.Context, orderJSON *models.OrderJSON) (bool, error) {
 	ret := _m.Called(ctx, orderJSON)
 
 	if len(ret) == 0 {
 		panic("no return value specified for Create")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderJSON) error); ok {
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderJSON) (bool, error)); ok {
+		return rf(ctx, orderJSON)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderJSON) bool); ok {
 		r0 = rf(ctx, orderJSON)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(bool)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, *models.OrderJSON) error); ok {
+		r1 = rf(ctx, orderJSON)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetAll provides a mock function with given fields: ctx
-func (_m *OrderRepository) GetAll(ctx context.Context) ([]models.OrderJSON, error) {
-	ret := _m.Called(ctx)
+// CreateBatch provides a mock function with given fields: ctx, orders
+func (_m *OrderRepository) CreateBatch(ctx context.Context, orders []*models.OrderJSON) ([]bool, []error, error) {
+	ret := _m.Called(ctx, orders)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetAll")
+		panic("no return value specified for CreateBatch")
 	}
 
-	var r0 []models.OrderJSON
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context) ([]models.OrderJSON, error)); ok {
-		return rf(ctx)
+	var r0 []bool
+	var r1 []error
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.OrderJSON) ([]bool, []error, error)); ok {
+		return rf(ctx, orders)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context) []models.OrderJSON); ok {
-		r0 = rf(ctx)
+	if rf, ok := ret.Get(0).(func(context.Context, []*models.OrderJSON) []bool); ok {
+		r0 = rf(ctx, orders)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.OrderJSON)
+			r0 = ret.Get(0).([]bool)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
-		r1 = rf(ctx)
+	if rf, ok := ret.Get(1).(func(context.Context, []*models.OrderJSON) []error); ok {
+		r1 = rf(ctx, orders)
 	} else {
-		r1 = ret.Error(1)
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]error)
+		}
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, []*models.OrderJSON) error); ok {
+		r2 = rf(ctx, orders)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // GetOrder provides a mock function with given fields: ctx, orderUID
@@ -92,6 +111,170 @@ func (_m *OrderRepository) GetOrder(ctx context.Context, orderUID string) (*mode
 	return r0, r1
 }
 
+// GetOrderStatus provides a mock function with given fields: ctx, orderUID
+func (_m *OrderRepository) GetOrderStatus(ctx context.Context, orderUID string) (models.OrderStatus, error) {
+	ret := _m.Called(ctx, orderUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderStatus")
+	}
+
+	var r0 models.OrderStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (models.OrderStatus, error)); ok {
+		return rf(ctx, orderUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) models.OrderStatus); ok {
+		r0 = rf(ctx, orderUID)
+	} else {
+		r0 = ret.Get(0).(models.OrderStatus)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderUIDByTrackNumber provides a mock function with given fields: ctx, trackNumber
+func (_m *OrderRepository) GetOrderUIDByTrackNumber(ctx context.Context, trackNumber string) (string, error) {
+	ret := _m.Called(ctx, trackNumber)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderUIDByTrackNumber")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (string, error)); ok {
+		return rf(ctx, trackNumber)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) string); ok {
+		r0 = rf(ctx, trackNumber)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, trackNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderUIDsByItem provides a mock function with given fields: ctx, chrtID, nmID
+func (_m *OrderRepository) GetOrderUIDsByItem(ctx context.Context, chrtID int64, nmID int64) ([]string, error) {
+	ret := _m.Called(ctx, chrtID, nmID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOrderUIDsByItem")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) ([]string, error)); ok {
+		return rf(ctx, chrtID, nmID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []string); ok {
+		r0 = rf(ctx, chrtID, nmID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, chrtID, nmID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetStatusHistory provides a mock function with given fields: ctx, orderUID
+func (_m *OrderRepository) GetStatusHistory(ctx context.Context, orderUID string) ([]models.StatusChange, error) {
+	ret := _m.Called(ctx, orderUID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetStatusHistory")
+	}
+
+	var r0 []models.StatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]models.StatusChange, error)); ok {
+		return rf(ctx, orderUID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []models.StatusChange); ok {
+		r0 = rf(ctx, orderUID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.StatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderUID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListOrders provides a mock function with given fields: ctx, filter
+func (_m *OrderRepository) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.OrderJSON, error) {
+	ret := _m.Called(ctx, filter)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ListOrders")
+	}
+
+	var r0 []models.OrderJSON
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) ([]models.OrderJSON, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, models.OrderFilter) []models.OrderJSON); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.OrderJSON)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, models.OrderFilter) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateStatus provides a mock function with given fields: ctx, orderUID, from, to, reason
+func (_m *OrderRepository) UpdateStatus(ctx context.Context, orderUID string, from models.OrderStatus, to models.OrderStatus, reason string) error {
+	ret := _m.Called(ctx, orderUID, from, to, reason)
+
+	if len(ret) == 0 {
+		panic("no return value specified for UpdateStatus")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, models.OrderStatus, models.OrderStatus, string) error); ok {
+		r0 = rf(ctx, orderUID, from, to, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewOrderRepository creates a new instance of OrderRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewOrderRepository(t interface {
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -16,6 +17,9 @@ type Manager struct {
 	mu      sync.RWMutex
 	closers []Closer
 	logger  *logrus.Logger
+
+	shuttingDown atomic.Bool
+	drainDelay   time.Duration
 }
 
 // NewManager конструктор Manager
@@ -32,6 +36,17 @@ func (m *Manager) Add(closer Closer) {
 	m.closers = append(m.closers, closer)
 }
 
+// SetDrainDelay задаёт паузу между началом остановки и закрытием ресурсов,
+// за которую балансировщик успевает увидеть, что сервис больше не готов
+func (m *Manager) SetDrainDelay(delay time.Duration) {
+	m.drainDelay = delay
+}
+
+// ShuttingDown сообщает, началась ли остановка сервиса
+func (m *Manager) ShuttingDown() bool {
+	return m.shuttingDown.Load()
+}
+
 // WaitForSignal ожидает сигнал SIGINT SIGTERM для graceful shutdown
 func (m *Manager) WaitForSignal() {
 	sigChan := make(chan os.Signal, 1)
@@ -48,6 +63,15 @@ func (m *Manager) WaitForSignal() {
 
 // Shutdown закрывает все соединения в обратном порядке
 func (m *Manager) Shutdown(ctx context.Context) {
+	m.shuttingDown.Store(true)
+	if m.drainDelay > 0 {
+		m.logger.Infof("Draining for %s before closing resources", m.drainDelay)
+		select {
+		case <-time.After(m.drainDelay):
+		case <-ctx.Done():
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
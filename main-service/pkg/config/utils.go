@@ -1,7 +1,11 @@
 // Package config предоставляет общие функции для загрузки конфигурации
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"time"
+)
 
 // GetEnv получает значение переменной окружения
 func GetEnv(key, defaultValue string) string {
@@ -10,3 +14,39 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// GetEnvInt получает целочисленное значение переменной окружения
+func GetEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(GetEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvFloat получает значение с плавающей точкой из переменной окружения
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(GetEnv(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvDuration получает длительность из переменной окружения в формате time.ParseDuration
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(GetEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetEnvBool получает логическое значение переменной окружения в формате strconv.ParseBool
+func GetEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(GetEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,242 @@
+package contract
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"orders/pkg/models"
+	"time"
+)
+
+// OrderSchemaV1 — Avro-схема заказа версии 1
+//
+//go:embed schemas/order.v1.avsc
+var OrderSchemaV1 string
+
+// maxAvroLength ограничивает длину строки или блока массива, чтобы повреждённое
+// сообщение не заставило выделить лишнюю память
+const maxAvroLength = 1 << 20
+
+// avroField связывает поле записи Avro-схемы с полем структуры.
+// Поля перечислены в порядке схемы: в бинарной кодировке Avro имена полей не передаются.
+type avroField[T any] struct {
+	name string
+	ptr  func(*T) any
+}
+
+var orderAvroFields = []avroField[models.OrderJSON]{
+	{"order_uid", func(o *models.OrderJSON) any { return &o.OrderUID }},
+	{"track_number", func(o *models.OrderJSON) any { return &o.TrackNumber }},
+	{"entry", func(o *models.OrderJSON) any { return &o.Entry }},
+	{"locale", func(o *models.OrderJSON) any { return &o.Locale }},
+	{"internal_signature", func(o *models.OrderJSON) any { return &o.InternalSignature }},
+	{"customer_id", func(o *models.OrderJSON) any { return &o.CustomerID }},
+	{"delivery_service", func(o *models.OrderJSON) any { return &o.DeliveryService }},
+	{"shardkey", func(o *models.OrderJSON) any { return &o.ShardKey }},
+	{"sm_id", func(o *models.OrderJSON) any { return &o.SmID }},
+	{"date_created", func(o *models.OrderJSON) any { return &o.DateCreated }},
+	{"oof_shard", func(o *models.OrderJSON) any { return &o.OofShard }},
+	{"delivery", func(o *models.OrderJSON) any { return &o.Delivery }},
+	{"payment", func(o *models.OrderJSON) any { return &o.Payment }},
+	{"items", func(o *models.OrderJSON) any { return &o.Items }},
+}
+
+var deliveryAvroFields = []avroField[models.Delivery]{
+	{"name", func(d *models.Delivery) any { return &d.Name }},
+	{"phone", func(d *models.Delivery) any { return &d.Phone }},
+	{"zip", func(d *models.Delivery) any { return &d.Zip }},
+	{"city", func(d *models.Delivery) any { return &d.City }},
+	{"address", func(d *models.Delivery) any { return &d.Address }},
+	{"region", func(d *models.Delivery) any { return &d.Region }},
+	{"email", func(d *models.Delivery) any { return &d.Email }},
+}
+
+var paymentAvroFields = []avroField[models.Payment]{
+	{"transaction", func(p *models.Payment) any { return &p.Transaction }},
+	{"request_id", func(p *models.Payment) any { return &p.RequestID }},
+	{"currency", func(p *models.Payment) any { return &p.Currency }},
+	{"provider", func(p *models.Payment) any { return &p.Provider }},
+	{"amount", func(p *models.Payment) any { return &p.Amount }},
+	{"payment_dt", func(p *models.Payment) any { return &p.PaymentDT }},
+	{"bank", func(p *models.Payment) any { return &p.Bank }},
+	{"delivery_cost", func(p *models.Payment) any { return &p.DeliveryCost }},
+	{"goods_total", func(p *models.Payment) any { return &p.GoodsTotal }},
+	{"custom_fee", func(p *models.Payment) any { return &p.CustomFee }},
+}
+
+var itemAvroFields = []avroField[models.Item]{
+	{"chrt_id", func(i *models.Item) any { return &i.ChrtID }},
+	{"track_number", func(i *models.Item) any { return &i.TrackNumber }},
+	{"price", func(i *models.Item) any { return &i.Price }},
+	{"rid", func(i *models.Item) any { return &i.RID }},
+	{"name", func(i *models.Item) any { return &i.Name }},
+	{"sale", func(i *models.Item) any { return &i.Sale }},
+	{"size", func(i *models.Item) any { return &i.Size }},
+	{"total_price", func(i *models.Item) any { return &i.TotalPrice }},
+	{"nm_id", func(i *models.Item) any { return &i.NmID }},
+	{"brand", func(i *models.Item) any { return &i.Brand }},
+	{"status", func(i *models.Item) any { return &i.Status }},
+}
+
+// avroCodec кодирует заказ версии 1 в бинарный Avro по схеме OrderSchemaV1
+// (без заголовка Object Container File и без идентификатора схемы)
+type avroCodec struct{}
+
+func (avroCodec) Version() string    { return VersionV1 }
+func (avroCodec) Encoding() Encoding { return EncodingAvro }
+
+func (avroCodec) Encode(order *models.OrderJSON) ([]byte, error) {
+	var buf bytes.Buffer
+	writeAvroRecord(&buf, order, orderAvroFields)
+	return buf.Bytes(), nil
+}
+
+func (avroCodec) Decode(data []byte) (*models.OrderJSON, error) {
+	r := bytes.NewReader(data)
+	order := &models.OrderJSON{}
+	if err := readAvroRecord(r, order, orderAvroFields); err != nil {
+		return nil, fmt.Errorf("avro: %w", err)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("avro: %d unexpected trailing bytes", r.Len())
+	}
+	order.Delivery.OrderUID = order.OrderUID
+	return order, nil
+}
+
+func writeAvroRecord[T any](buf *bytes.Buffer, v *T, fields []avroField[T]) {
+	for _, field := range fields {
+		writeAvroValue(buf, field.ptr(v))
+	}
+}
+
+func writeAvroValue(buf *bytes.Buffer, ptr any) {
+	switch v := ptr.(type) {
+	case *string:
+		writeAvroLong(buf, int64(len(*v)))
+		buf.WriteString(*v)
+	case *int:
+		writeAvroLong(buf, int64(*v))
+	case *int64:
+		writeAvroLong(buf, *v)
+	case *time.Time:
+		writeAvroLong(buf, v.UnixMicro())
+	case *models.Delivery:
+		writeAvroRecord(buf, v, deliveryAvroFields)
+	case *models.Payment:
+		writeAvroRecord(buf, v, paymentAvroFields)
+	case *[]models.Item:
+		if len(*v) > 0 {
+			writeAvroLong(buf, int64(len(*v)))
+			for i := range *v {
+				writeAvroRecord(buf, &(*v)[i], itemAvroFields)
+			}
+		}
+		writeAvroLong(buf, 0)
+	default:
+		panic(fmt.Sprintf("contract: unsupported avro field type %T", ptr))
+	}
+}
+
+// writeAvroLong пишет int и long Avro: zig-zag varint, как binary.AppendVarint
+func writeAvroLong(buf *bytes.Buffer, v int64) {
+	buf.Write(binary.AppendVarint(nil, v))
+}
+
+func readAvroRecord[T any](r *bytes.Reader, v *T, fields []avroField[T]) error {
+	for _, field := range fields {
+		if err := readAvroValue(r, field.ptr(v)); err != nil {
+			return fmt.Errorf("%s: %w", field.name, err)
+		}
+	}
+	return nil
+}
+
+func readAvroValue(r *bytes.Reader, ptr any) error {
+	switch v := ptr.(type) {
+	case *string:
+		n, err := readAvroLength(r)
+		if err != nil {
+			return err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+		*v = string(b)
+	case *int:
+		n, err := readAvroLong(r)
+		if err != nil {
+			return err
+		}
+		*v = int(n)
+	case *int64:
+		n, err := readAvroLong(r)
+		if err != nil {
+			return err
+		}
+		*v = n
+	case *time.Time:
+		n, err := readAvroLong(r)
+		if err != nil {
+			return err
+		}
+		*v = time.UnixMicro(n).UTC()
+	case *models.Delivery:
+		return readAvroRecord(r, v, deliveryAvroFields)
+	case *models.Payment:
+		return readAvroRecord(r, v, paymentAvroFields)
+	case *[]models.Item:
+		for {
+			count, err := readAvroLong(r)
+			if err != nil {
+				return err
+			}
+			if count == 0 {
+				return nil
+			}
+			if count < 0 {
+				// Отрицательный счётчик блока сопровождается размером блока в байтах
+				count = -count
+				if _, err := readAvroLong(r); err != nil {
+					return err
+				}
+			}
+			if count > maxAvroLength {
+				return fmt.Errorf("array block of %d items is too large", count)
+			}
+			for range count {
+				var item models.Item
+				if err := readAvroRecord(r, &item, itemAvroFields); err != nil {
+					return err
+				}
+				*v = append(*v, item)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("contract: unsupported avro field type %T", ptr))
+	}
+	return nil
+}
+
+func readAvroLong(r *bytes.Reader) (int64, error) {
+	n, err := binary.ReadVarint(r)
+	if errors.Is(err, io.EOF) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func readAvroLength(r *bytes.Reader) (int64, error) {
+	n, err := readAvroLong(r)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > maxAvroLength || n > int64(r.Len()) {
+		return 0, fmt.Errorf("invalid length %d", n)
+	}
+	return n, nil
+}
@@ -0,0 +1,127 @@
+// Package contract описывает версионированный контракт сообщений с заказами в Kafka.
+// Версия контракта и кодировка передаются в заголовках сообщения, а потребитель
+// выбирает декодер для каждого сообщения по этим заголовкам.
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"orders/pkg/models"
+	"sort"
+)
+
+// Заголовки, которыми производитель помечает сообщение с заказом
+const (
+	// HeaderSchemaVersion — версия контракта сообщения
+	HeaderSchemaVersion = "x-schema-version"
+	// HeaderEncoding — кодировка тела сообщения
+	HeaderEncoding = "x-encoding"
+)
+
+// Версии контракта
+const (
+	// VersionV1 — заказ со структурой models.OrderJSON; date_created — RFC 3339 в JSON,
+	// google.protobuf.Timestamp в Protobuf и timestamp-micros в Avro
+	VersionV1 = "1"
+	// CurrentVersion — версия, которую должны использовать производители
+	CurrentVersion = VersionV1
+)
+
+// Encoding — кодировка тела сообщения
+type Encoding string
+
+// Поддерживаемые кодировки
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingProtobuf Encoding = "protobuf"
+	EncodingAvro     Encoding = "avro"
+)
+
+// Ошибки выбора кодека
+var (
+	// ErrUnsupportedVersion — версия контракта неизвестна этой сборке сервиса
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+	// ErrUnsupportedEncoding — кодировка не поддерживается для версии контракта
+	ErrUnsupportedEncoding = errors.New("unsupported encoding")
+)
+
+// Codec кодирует и декодирует заказ одной версии контракта в одной кодировке
+type Codec interface {
+	Version() string
+	Encoding() Encoding
+	Encode(order *models.OrderJSON) ([]byte, error)
+	Decode(data []byte) (*models.OrderJSON, error)
+}
+
+type codecKey struct {
+	version  string
+	encoding Encoding
+}
+
+// Registry хранит кодеки поддерживаемых версий контракта
+type Registry struct {
+	codecs map[codecKey]Codec
+}
+
+// NewRegistry создает Registry со всеми кодеками, известными этой сборке
+func NewRegistry() *Registry {
+	r := &Registry{codecs: make(map[codecKey]Codec)}
+	r.Register(jsonCodec{})
+	r.Register(protobufCodec{})
+	r.Register(avroCodec{})
+	return r
+}
+
+// Register добавляет кодек, заменяя ранее зарегистрированный для той же версии и кодировки
+func (r *Registry) Register(codec Codec) {
+	r.codecs[codecKey{codec.Version(), codec.Encoding()}] = codec
+}
+
+// Codec возвращает кодек для версии и кодировки
+func (r *Registry) Codec(version string, encoding Encoding) (Codec, error) {
+	if codec, ok := r.codecs[codecKey{version, encoding}]; ok {
+		return codec, nil
+	}
+	for key := range r.codecs {
+		if key.version == version {
+			return nil, fmt.Errorf("%w %q for schema version %s", ErrUnsupportedEncoding, encoding, version)
+		}
+	}
+	return nil, fmt.Errorf("%w %q (supported: %v)", ErrUnsupportedVersion, version, r.Versions())
+}
+
+// Resolve выбирает кодек по заголовкам сообщения. Сообщения без заголовков
+// считаются JSON версии 1: так их отправляли производители до появления контракта.
+func (r *Registry) Resolve(headers map[string]string) (Codec, error) {
+	version := headers[HeaderSchemaVersion]
+	if version == "" {
+		version = VersionV1
+	}
+	encoding := Encoding(headers[HeaderEncoding])
+	if encoding == "" {
+		encoding = EncodingJSON
+	}
+	return r.Codec(version, encoding)
+}
+
+// Versions возвращает поддерживаемые версии контракта по возрастанию
+func (r *Registry) Versions() []string {
+	seen := make(map[string]bool)
+	versions := make([]string, 0, len(r.codecs))
+	for key := range r.codecs {
+		if !seen[key.version] {
+			seen[key.version] = true
+			versions = append(versions, key.version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Headers возвращает заголовки, описывающие сообщение, закодированное codec
+func Headers(codec Codec) map[string]string {
+	return map[string]string{
+		HeaderSchemaVersion: codec.Version(),
+		HeaderEncoding:      string(codec.Encoding()),
+	}
+}
@@ -0,0 +1,147 @@
+package contract
+
+import (
+	"encoding/json"
+	"orders/pkg/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOrder() *models.OrderJSON {
+	return &models.OrderJSON{
+		OrderUID:        "b563feb7b2b84b6test",
+		TrackNumber:     "WBILMTESTTRACK",
+		Entry:           "WBIL",
+		Locale:          "en",
+		CustomerID:      "test",
+		DeliveryService: "meest",
+		ShardKey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2021, 11, 26, 6, 22, 19, 123456000, time.UTC),
+		OofShard:        "1",
+		Delivery: models.Delivery{
+			OrderUID: "b563feb7b2b84b6test",
+			Name:     "Test Testov",
+			Phone:    "+9720000000",
+			Zip:      "2639809",
+			City:     "Kiryat Mozkin",
+			Address:  "Ploshad Mira 15",
+			Region:   "Kraiot",
+			Email:    "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction:  "b563feb7b2b84b6test",
+			Currency:     "USD",
+			Provider:     "wbpay",
+			Amount:       1817,
+			PaymentDT:    1637907727,
+			Bank:         "alpha",
+			DeliveryCost: 1500,
+			GoodsTotal:   317,
+		},
+		Items: []models.Item{
+			{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, RID: "ab4219087a764ae0btest",
+				Name: "Mascaras", Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202},
+			{ChrtID: 9934931, TrackNumber: "WBILMTESTTRACK", Price: -1, RID: "ab4219087a764ae0btes2", Name: "Кисть"},
+		},
+	}
+}
+
+func TestRegistry_RoundTrip(t *testing.T) {
+	registry := NewRegistry()
+	for _, encoding := range []Encoding{EncodingJSON, EncodingProtobuf, EncodingAvro} {
+		t.Run(string(encoding), func(t *testing.T) {
+			codec, err := registry.Codec(CurrentVersion, encoding)
+			require.NoError(t, err)
+
+			data, err := codec.Encode(testOrder())
+			require.NoError(t, err)
+
+			decoded, err := registry.Resolve(Headers(codec))
+			require.NoError(t, err)
+			order, err := decoded.Decode(data)
+			require.NoError(t, err)
+			assert.Equal(t, testOrder(), order)
+		})
+	}
+}
+
+func TestRegistry_ResolveLegacyMessage(t *testing.T) {
+	codec, err := NewRegistry().Resolve(map[string]string{})
+
+	require.NoError(t, err)
+	assert.Equal(t, VersionV1, codec.Version())
+	assert.Equal(t, EncodingJSON, codec.Encoding())
+}
+
+func TestRegistry_ResolveUnsupported(t *testing.T) {
+	registry := NewRegistry()
+
+	_, err := registry.Resolve(map[string]string{HeaderSchemaVersion: "2"})
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+
+	_, err = registry.Resolve(map[string]string{HeaderSchemaVersion: "1", HeaderEncoding: "xml"})
+	assert.ErrorIs(t, err, ErrUnsupportedEncoding)
+}
+
+func TestAvroCodec_Truncated(t *testing.T) {
+	data, err := avroCodec{}.Encode(testOrder())
+	require.NoError(t, err)
+
+	_, err = avroCodec{}.Decode(data[:len(data)-3])
+	assert.Error(t, err)
+
+	_, err = avroCodec{}.Decode(append(data, 0))
+	assert.Error(t, err)
+}
+
+// avscRecord — часть Avro-схемы записи, нужная для сверки имён полей
+type avscRecord struct {
+	Fields []struct {
+		Name string          `json:"name"`
+		Type json.RawMessage `json:"type"`
+	} `json:"fields"`
+	Items json.RawMessage `json:"items"`
+}
+
+func (r avscRecord) names() []string {
+	names := make([]string, 0, len(r.Fields))
+	for _, f := range r.Fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// Поля кодека должны идти в том же порядке, что и в опубликованной схеме
+func TestAvroCodec_MatchesSchema(t *testing.T) {
+	var order avscRecord
+	require.NoError(t, json.Unmarshal([]byte(OrderSchemaV1), &order))
+
+	nested := make(map[string]avscRecord)
+	for _, f := range order.Fields {
+		var r avscRecord
+		if json.Unmarshal(f.Type, &r) != nil {
+			continue
+		}
+		if r.Items != nil {
+			require.NoError(t, json.Unmarshal(r.Items, &r))
+		}
+		nested[f.Name] = r
+	}
+
+	assert.Equal(t, fieldNames(orderAvroFields), order.names())
+	assert.Equal(t, fieldNames(deliveryAvroFields), nested["delivery"].names())
+	assert.Equal(t, fieldNames(paymentAvroFields), nested["payment"].names())
+	assert.Equal(t, fieldNames(itemAvroFields), nested["items"].names())
+}
+
+func fieldNames[T any](fields []avroField[T]) []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+	return names
+}
@@ -0,0 +1,24 @@
+package contract
+
+import (
+	"encoding/json"
+	"orders/pkg/models"
+)
+
+// jsonCodec кодирует заказ версии 1 в JSON, как его принимает HTTP API
+type jsonCodec struct{}
+
+func (jsonCodec) Version() string    { return VersionV1 }
+func (jsonCodec) Encoding() Encoding { return EncodingJSON }
+
+func (jsonCodec) Encode(order *models.OrderJSON) ([]byte, error) {
+	return json.Marshal(order)
+}
+
+func (jsonCodec) Decode(data []byte) (*models.OrderJSON, error) {
+	var order *models.OrderJSON
+	if err := json.Unmarshal(data, &order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
@@ -0,0 +1,148 @@
+package contract
+
+import (
+	"errors"
+	ordersv1 "orders/api/orders/v1"
+	"orders/pkg/models"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protobufCodec кодирует заказ версии 1 сообщением orders.v1.Order из api/orders/v1/orders.proto
+type protobufCodec struct{}
+
+func (protobufCodec) Version() string    { return VersionV1 }
+func (protobufCodec) Encoding() Encoding { return EncodingProtobuf }
+
+func (protobufCodec) Encode(order *models.OrderJSON) ([]byte, error) {
+	return proto.Marshal(ToProto(order))
+}
+
+func (protobufCodec) Decode(data []byte) (*models.OrderJSON, error) {
+	var order ordersv1.Order
+	if err := proto.Unmarshal(data, &order); err != nil {
+		return nil, err
+	}
+	if order.GetDateCreated() == nil {
+		return nil, errors.New("date_created is required")
+	}
+	return FromProto(&order), nil
+}
+
+// ToProto переводит заказ в сообщение orders.v1.Order
+func ToProto(order *models.OrderJSON) *ordersv1.Order {
+	items := make([]*ordersv1.Item, 0, len(order.Items))
+	for _, item := range order.Items {
+		items = append(items, &ordersv1.Item{
+			ChrtId:      item.ChrtID,
+			TrackNumber: item.TrackNumber,
+			Price:       int64(item.Price),
+			Rid:         item.RID,
+			Name:        item.Name,
+			Sale:        int32(item.Sale),
+			Size:        item.Size,
+			TotalPrice:  int64(item.TotalPrice),
+			NmId:        item.NmID,
+			Brand:       item.Brand,
+			Status:      int32(item.Status),
+		})
+	}
+
+	return &ordersv1.Order{
+		OrderUid:          order.OrderUID,
+		TrackNumber:       order.TrackNumber,
+		Entry:             order.Entry,
+		Locale:            order.Locale,
+		InternalSignature: order.InternalSignature,
+		CustomerId:        order.CustomerID,
+		DeliveryService:   order.DeliveryService,
+		Shardkey:          order.ShardKey,
+		SmId:              int32(order.SmID),
+		DateCreated:       timestamppb.New(order.DateCreated),
+		OofShard:          order.OofShard,
+		Status:            string(order.Status),
+		Delivery: &ordersv1.Delivery{
+			Name:    order.Delivery.Name,
+			Phone:   order.Delivery.Phone,
+			Zip:     order.Delivery.Zip,
+			City:    order.Delivery.City,
+			Address: order.Delivery.Address,
+			Region:  order.Delivery.Region,
+			Email:   order.Delivery.Email,
+		},
+		Payment: &ordersv1.Payment{
+			Transaction:  order.Payment.Transaction,
+			RequestId:    order.Payment.RequestID,
+			Currency:     order.Payment.Currency,
+			Provider:     order.Payment.Provider,
+			Amount:       int64(order.Payment.Amount),
+			PaymentDt:    order.Payment.PaymentDT,
+			Bank:         order.Payment.Bank,
+			DeliveryCost: int64(order.Payment.DeliveryCost),
+			GoodsTotal:   int64(order.Payment.GoodsTotal),
+			CustomFee:    int64(order.Payment.CustomFee),
+		},
+		Items: items,
+	}
+}
+
+// FromProto переводит сообщение orders.v1.Order в заказ. Delivery.OrderUID
+// в сообщении не передаётся и заполняется из order_uid.
+func FromProto(order *ordersv1.Order) *models.OrderJSON {
+	items := make([]models.Item, 0, len(order.GetItems()))
+	for _, item := range order.GetItems() {
+		items = append(items, models.Item{
+			ChrtID:      item.GetChrtId(),
+			TrackNumber: item.GetTrackNumber(),
+			Price:       int(item.GetPrice()),
+			RID:         item.GetRid(),
+			Name:        item.GetName(),
+			Sale:        int(item.GetSale()),
+			Size:        item.GetSize(),
+			TotalPrice:  int(item.GetTotalPrice()),
+			NmID:        item.GetNmId(),
+			Brand:       item.GetBrand(),
+			Status:      int(item.GetStatus()),
+		})
+	}
+
+	delivery, payment := order.GetDelivery(), order.GetPayment()
+	return &models.OrderJSON{
+		OrderUID:          order.GetOrderUid(),
+		TrackNumber:       order.GetTrackNumber(),
+		Entry:             order.GetEntry(),
+		Locale:            order.GetLocale(),
+		InternalSignature: order.GetInternalSignature(),
+		CustomerID:        order.GetCustomerId(),
+		DeliveryService:   order.GetDeliveryService(),
+		ShardKey:          order.GetShardkey(),
+		SmID:              int(order.GetSmId()),
+		DateCreated:       order.GetDateCreated().AsTime(),
+		OofShard:          order.GetOofShard(),
+		Status:            models.OrderStatus(order.GetStatus()),
+		Delivery: models.Delivery{
+			OrderUID: order.GetOrderUid(),
+			Name:     delivery.GetName(),
+			Phone:    delivery.GetPhone(),
+			Zip:      delivery.GetZip(),
+			City:     delivery.GetCity(),
+			Address:  delivery.GetAddress(),
+			Region:   delivery.GetRegion(),
+			Email:    delivery.GetEmail(),
+		},
+		Payment: models.Payment{
+			Transaction:  payment.GetTransaction(),
+			RequestID:    payment.GetRequestId(),
+			Currency:     payment.GetCurrency(),
+			Provider:     payment.GetProvider(),
+			Amount:       int(payment.GetAmount()),
+			PaymentDT:    payment.GetPaymentDt(),
+			Bank:         payment.GetBank(),
+			DeliveryCost: int(payment.GetDeliveryCost()),
+			GoodsTotal:   int(payment.GetGoodsTotal()),
+			CustomFee:    int(payment.GetCustomFee()),
+		},
+		Items: items,
+	}
+}
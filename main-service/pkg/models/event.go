@@ -0,0 +1,23 @@
+// Package models содержит структуры данных заказов
+package models
+
+import "time"
+
+// Типы доменных событий заказа
+const (
+	EventOrderCreated = "order.created"
+	EventOrderUpdated = "order.updated"
+)
+
+// OrderEvent — доменное событие заказа для внешних систем.
+// Order заполняется, когда заказ создан или заменён целиком; при смене статуса
+// передаются только Status, PreviousStatus и Reason.
+type OrderEvent struct {
+	Type           string      `json:"type"`
+	OrderUID       string      `json:"order_uid"`
+	Status         OrderStatus `json:"status"`
+	PreviousStatus OrderStatus `json:"previous_status,omitempty"`
+	Reason         string      `json:"reason,omitempty"`
+	Order          *OrderJSON  `json:"order,omitempty"`
+	OccurredAt     time.Time   `json:"occurred_at"`
+}
@@ -0,0 +1,28 @@
+// Package models содержит структуры данных заказов
+package models
+
+import "time"
+
+// OrderFilter содержит параметры фильтрации и пагинации списка заказов
+type OrderFilter struct {
+	CustomerID      string
+	DeliveryService string
+	Locale          string
+	Currency        string
+	DateFrom        time.Time
+	DateTo          time.Time
+	Cursor          *Cursor
+	Limit           int
+}
+
+// Cursor указывает на последний заказ предыдущей страницы
+type Cursor struct {
+	DateCreated time.Time
+	OrderUID    string
+}
+
+// OrderPage представляет страницу списка заказов
+type OrderPage struct {
+	Orders     []OrderJSON `json:"orders"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
@@ -16,6 +16,8 @@ type OrderJSON struct {
 	SmID              int       `json:"sm_id" validate:"min=0,max=999"`
 	DateCreated       time.Time `json:"date_created" validate:"required"`
 	OofShard          string    `json:"oof_shard" validate:"max=10"`
+	// Status заполняется из БД; при создании заказ всегда получает статус created
+	Status OrderStatus `json:"status,omitempty" validate:"-"`
 
 	Delivery Delivery `json:"delivery" validate:"required"`
 	Payment  Payment  `json:"payment" validate:"required"`
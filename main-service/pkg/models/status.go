@@ -0,0 +1,51 @@
+// Package models содержит структуры данных заказов
+package models
+
+import "time"
+
+// OrderStatus — статус заказа
+type OrderStatus string
+
+// Статусы заказа
+const (
+	StatusCreated    OrderStatus = "created"
+	StatusPaid       OrderStatus = "paid"
+	StatusAssembling OrderStatus = "assembling"
+	StatusShipped    OrderStatus = "shipped"
+	StatusDelivered  OrderStatus = "delivered"
+	StatusCancelled  OrderStatus = "cancelled"
+	StatusReturned   OrderStatus = "returned"
+)
+
+// IsValid сообщает, является ли статус одним из известных
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case StatusCreated, StatusPaid, StatusAssembling, StatusShipped,
+		StatusDelivered, StatusCancelled, StatusReturned:
+		return true
+	}
+	return false
+}
+
+// StatusChange — запись истории статусов заказа.
+// From пуст для первой записи, созданной вместе с заказом.
+type StatusChange struct {
+	From      OrderStatus `json:"from,omitempty"`
+	To        OrderStatus `json:"to"`
+	Reason    string      `json:"reason,omitempty"`
+	ChangedAt time.Time   `json:"changed_at"`
+}
+
+// StatusEvent — событие смены статуса заказа, приходящее из Kafka
+type StatusEvent struct {
+	OrderUID string      `json:"order_uid" validate:"required,min=10,max=50"`
+	Status   OrderStatus `json:"status" validate:"required"`
+	Reason   string      `json:"reason" validate:"max=255"`
+}
+
+// StatusHistory — ответ API с текущим статусом и историей его изменений
+type StatusHistory struct {
+	OrderUID string         `json:"order_uid"`
+	Status   OrderStatus    `json:"status"`
+	History  []StatusChange `json:"history"`
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"orders/internal/health"
 	"orders/internal/subs"
 	utilsCfg "orders/pkg/config"
 
@@ -50,19 +51,23 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 type Server struct {
 	httpServer *http.Server
 	handler    *subs.Handler
+	health     *health.Health
 	logger     *logrus.Logger
 	name       string
 }
 
 // NewServer создает новый HTTP сервер
-func NewServer(handler *subs.Handler, logger *logrus.Logger) *Server {
+func NewServer(handler *subs.Handler, checks *health.Health, logger *logrus.Logger) *Server {
 	port := utilsCfg.GetEnv("PORT", "8080")
 	server := &http.Server{
 		Addr: fmt.Sprintf(":%s", port),
 	}
+	// Потоки новых заказов не завершаются сами, поэтому Shutdown закрывает их явно
+	server.RegisterOnShutdown(handler.CloseStreams)
 	return &Server{
 		httpServer: server,
 		handler:    handler,
+		health:     checks,
 		logger:     logger,
 		name:       "http server",
 	}
@@ -72,7 +77,17 @@ func NewServer(handler *subs.Handler, logger *logrus.Logger) *Server {
 func (s *Server) Run() {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", s.health.LivenessFromHTTP)
+	mux.HandleFunc("/readyz", s.health.ReadinessFromHTTP)
 	mux.HandleFunc("/order/{order_uid}", s.handler.GetOrderFromHTTP)
+	mux.HandleFunc("/order/by-track/{track_number}", s.handler.GetOrderByTrackFromHTTP)
+	// Шаблон /order/{order_uid}/history пересекался бы с /order/by-track/{track_number},
+	// поэтому вложенные ресурсы заказа разбирает обработчик
+	mux.HandleFunc("/order/{order_uid}/{resource}", s.handler.GetOrderResourceFromHTTP)
+	mux.HandleFunc("/orders", s.handler.ListOrdersFromHTTP)
+	mux.HandleFunc("/orders/stream", s.handler.StreamOrdersFromHTTP)
+	mux.HandleFunc("POST /order", s.handler.CreateOrderFromHTTP)
+	mux.HandleFunc("POST /orders:batch", s.handler.CreateOrdersBatchFromHTTP)
 
 	s.httpServer.Handler = MetricsMiddleware(mux)
 
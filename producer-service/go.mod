@@ -3,15 +3,30 @@ module producer-service
 go 1.24.1
 
 require (
+	github.com/brianvoe/gofakeit/v7 v7.14.0
 	github.com/joho/godotenv v1.5.1
 	github.com/segmentio/kafka-go v0.4.49
 	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.11.1
+	orders v0.0.0-00010101000000-000000000000
 )
 
 require (
-	github.com/brianvoe/gofakeit/v7 v7.14.0 // indirect
-	github.com/klauspost/compress v1.15.9 // indirect
+	golang.org/x/net v0.48.0 // indirect
+	golang.org/x/text v0.32.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20251202230838-ff82c1b0f217 // indirect
+	google.golang.org/grpc v1.79.3 // indirect
+	google.golang.org/protobuf v1.36.10 // indirect
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/pierrec/lz4/v4 v4.1.15 // indirect
-	github.com/stretchr/testify v1.10.0 // indirect
-	golang.org/x/sys v0.29.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+// Контракт сообщений и модель заказа общие с main-service
+replace orders => ../main-service
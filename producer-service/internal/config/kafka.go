@@ -12,7 +12,9 @@ type KafkaConfig struct {
 	KafkaURL      string
 	Topic         string
 	GroupConsumer string
-	Logger        *logrus.Logger
+	// Encoding — кодировка тела сообщений с заказами: json, protobuf или avro
+	Encoding string
+	Logger   *logrus.Logger
 }
 
 func LoadKafkaConfig(logger *logrus.Logger) (*KafkaConfig, error) {
@@ -25,6 +27,7 @@ func LoadKafkaConfig(logger *logrus.Logger) (*KafkaConfig, error) {
 		KafkaURL:      GetEnv("KAFKA_URL", "kafka:9092"),
 		Topic:         GetEnv("TEST_TOPIC", "test_topic"),
 		GroupConsumer: GetEnv("GROUP_ID", "test_group"),
+		Encoding:      GetEnv("KAFKA_ENCODING", "json"),
 		Logger:        logger,
 	}
 	return config, nil
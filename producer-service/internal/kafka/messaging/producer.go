@@ -37,6 +37,9 @@ func (p *kafkaProducer) ProduceMessage(ctx context.Context, topic string, msg Me
 		Key:   msg.Key,
 		Value: msg.Value,
 	}
+	for key, value := range msg.Headers {
+		kafkaMsg.Headers = append(kafkaMsg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
 	return p.writer.WriteMessages(ctx, kafkaMsg)
 }
 
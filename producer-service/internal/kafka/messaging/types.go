@@ -7,8 +7,9 @@ import (
 
 // Message представляет сообщение Kafka
 type Message struct {
-	Key   []byte
-	Value []byte
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
 }
 
 // Producer определяет интерфейс для отправки сообщений в Kafka
@@ -3,8 +3,8 @@ package sender
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"orders/pkg/contract"
 	"producer-service/internal/kafka/messaging"
 	"time"
 
@@ -16,15 +16,23 @@ type OrderSender struct {
 	producer messaging.Producer
 	logger   *logrus.Logger
 	topic    string
+	// codec кодирует заказы по контракту main-service
+	codec contract.Codec
 }
 
-// NewOrderSender создает новый экземпляр OrderSender
-func NewOrderSender(producer messaging.Producer, topic string, logger *logrus.Logger) *OrderSender {
+// NewOrderSender создает новый экземпляр OrderSender.
+// Заказы кодируются кодеком текущей версии контракта в кодировке encoding.
+func NewOrderSender(producer messaging.Producer, topic string, encoding contract.Encoding, logger *logrus.Logger) (*OrderSender, error) {
+	codec, err := contract.NewRegistry().Codec(contract.CurrentVersion, encoding)
+	if err != nil {
+		return nil, fmt.Errorf("sender.NewOrderSender: %w", err)
+	}
 	return &OrderSender{
 		producer: producer,
 		logger:   logger,
 		topic:    topic,
-	}
+		codec:    codec,
+	}, nil
 }
 
 // Send запускает периодическую отправку заказов
@@ -48,20 +56,15 @@ func (s *OrderSender) Send(ctx context.Context) {
 // SendOnes отправляет один сгенерированный заказ
 func (s *OrderSender) SendOnes(ctx context.Context) error {
 	order := createRandomOrder()
-	jsonBytes, err := json.Marshal(order)
+	value, err := s.codec.Encode(&order)
 	if err != nil {
-		return fmt.Errorf("sender.Send: Failed to create JSON: %v", err)
-
-	}
-
-	var test map[string]interface{}
-	if err := json.Unmarshal(jsonBytes, &test); err != nil {
-		return fmt.Errorf("sender.Send: Generated JSON is invalid: %v", err)
+		return fmt.Errorf("sender.Send: failed to encode order: %v", err)
 	}
 
 	msg := messaging.Message{
-		Key:   []byte(order.OrderUID),
-		Value: jsonBytes,
+		Key:     []byte(order.OrderUID),
+		Value:   value,
+		Headers: contract.Headers(s.codec),
 	}
 
 	if err := s.producer.ProduceMessage(context.Background(), s.topic, msg); err != nil {
@@ -0,0 +1,62 @@
+package sender
+
+import (
+	"context"
+	"io"
+	"orders/pkg/contract"
+	"producer-service/internal/kafka/messaging"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProducer запоминает отправленные сообщения
+type recordingProducer struct {
+	sent []messaging.Message
+}
+
+func (p *recordingProducer) ProduceMessage(ctx context.Context, topic string, msg messaging.Message) error {
+	p.sent = append(p.sent, msg)
+	return nil
+}
+
+func (p *recordingProducer) Close(ctx context.Context) error { return nil }
+
+func (p *recordingProducer) Name() string { return "recording producer" }
+
+// TestOrderSender_SendOnes_DecodedByConsumerRegistry проверяет, что сообщение производителя
+// декодирует тот же реестр контракта, которым пользуется потребитель main-service
+func TestOrderSender_SendOnes_DecodedByConsumerRegistry(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	for _, encoding := range []contract.Encoding{contract.EncodingJSON, contract.EncodingProtobuf, contract.EncodingAvro} {
+		t.Run(string(encoding), func(t *testing.T) {
+			producer := &recordingProducer{}
+			sender, err := NewOrderSender(producer, "orders", encoding, logger)
+			require.NoError(t, err)
+
+			require.NoError(t, sender.SendOnes(context.Background()))
+			require.Len(t, producer.sent, 1)
+			msg := producer.sent[0]
+
+			codec, err := contract.NewRegistry().Resolve(msg.Headers)
+			require.NoError(t, err)
+			assert.Equal(t, encoding, codec.Encoding())
+			order, err := codec.Decode(msg.Value)
+			require.NoError(t, err)
+			assert.Equal(t, string(msg.Key), order.OrderUID)
+			// Без потерь при декодировании заказ кодируется в то же тело
+			value, err := codec.Encode(order)
+			require.NoError(t, err)
+			assert.Equal(t, msg.Value, value)
+		})
+	}
+}
+
+func TestNewOrderSender_UnsupportedEncoding(t *testing.T) {
+	_, err := NewOrderSender(&recordingProducer{}, "orders", "xml", logrus.New())
+	assert.ErrorIs(t, err, contract.ErrUnsupportedEncoding)
+}
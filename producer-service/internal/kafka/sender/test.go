@@ -1,18 +1,18 @@
 package sender
 
 import (
-	"producer-service/pkg/models"
+	"orders/pkg/models"
 	"strconv"
 	"time"
 
 	"github.com/brianvoe/gofakeit/v7"
 )
 
-func createRandomOrder() models.Order {
+func createRandomOrder() models.OrderJSON {
 	orderUID := gofakeit.UUID()
 	trackNumber := gofakeit.Regex("[A-Z0-9]{10,15}")
 
-	order := models.Order{
+	order := models.OrderJSON{
 		OrderUID:    orderUID,
 		TrackNumber: trackNumber,
 		Entry:       gofakeit.Regex("ENT[A-Z0-9]{3}"),
@@ -58,7 +58,7 @@ func createRandomOrder() models.Order {
 		DeliveryService:   gofakeit.Company(),
 		ShardKey:          strconv.Itoa(gofakeit.Number(1, 9)),
 		SmID:              gofakeit.Number(1, 100),
-		DateCreated:       gofakeit.Date().Format(time.RFC3339),
+		DateCreated:       gofakeit.Date().UTC().Truncate(time.Second),
 		OofShard:          strconv.Itoa(gofakeit.Number(1, 9)),
 	}
     if order.Payment.PaymentDT < 0 {
@@ -72,7 +72,7 @@ func createRandomOrder() models.Order {
 	return order
 }
 
-func introduceValidationError(order *models.Order) {
+func introduceValidationError(order *models.OrderJSON) {
 	switch gofakeit.Number(1, 10) {
 	case 1:
 		order.OrderUID = "short" // <10 символов
@@ -93,6 +93,6 @@ func introduceValidationError(order *models.Order) {
 	case 9:
 		order.SmID = 1000 // больше max=999
 	case 10:
-		order.DateCreated = "invalid-date" // невалидная дата
+		order.DateCreated = time.Time{} // пустая дата
 	}
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"orders/pkg/contract"
 	"producer-service/internal/config"
 	"producer-service/internal/kafka/messaging"
 	"producer-service/internal/kafka/sender"
@@ -33,7 +34,11 @@ func main() {
 	producer := messaging.NewKafkaProducer([]string{kafkaCfg.KafkaURL})
 	manager.Add(producer)
 
-	orderSender := sender.NewOrderSender(producer, kafkaCfg.Topic, logger)
+	orderSender, err := sender.NewOrderSender(producer, kafkaCfg.Topic, contract.Encoding(kafkaCfg.Encoding), logger)
+	if err != nil {
+		logger.Errorf("main: %v", err)
+		return
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()